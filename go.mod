@@ -3,10 +3,11 @@ module github.com/ancientlore/whisper
 go 1.25.0
 
 require (
-	github.com/NYTimes/gziphandler v1.1.1
+	github.com/andybalholm/brotli v1.2.0
 	github.com/ancientlore/cachefs v1.1.0
 	github.com/ancientlore/flagenv v1.0.0
 	github.com/golang/groupcache v0.0.0-20241129210726-2c02b8208cf8
+	github.com/klauspost/compress v1.18.0
 	github.com/pelletier/go-toml/v2 v2.4.2
 	github.com/russross/blackfriday/v2 v2.1.0
 )
@@ -10,7 +10,8 @@ See the example folder for a sample site layout. In general, whisper uses conven
 Conventions used by this server include:
 
 * The template folder holds HTML templates, using Go's html/template package. These templates are used for rendering content but never served directly.
-* A sitemap.txt can be created as a template. See the example for details.
+* The data folder holds TOML, JSON, and CSV files that templates can read using the data function. These files are never served directly.
+* A sitemap.txt or sitemap.xml can be created as a template. See the example for details.
 * The default page for a folder is a Markdown file called index.md.
 * An optional whisper.cfg file holds settings should you want to preserve them.
 * Files 404.md and 500.md can be provided for custom errors.
@@ -20,6 +21,11 @@ Conventions used by this server include:
 Web pages are generally written in Markdown and use HTML templates to render into the site. The default template to use is called "default"; you must
 have a "default" template and an "image" template.  Templates are stored in the "template" folder.
 
+Templates in subfolders of the "template" folder, like "template/articles/default.html", are named by their path without the
+extension and override the template of the same name for pages in the matching folder, so a page in "articles" uses
+"articles/default" in place of "default". Deeper folders are checked first. A {{define}} in such a file must not reuse
+the name of another template, like "default", or the templates are not loaded.
+
 NOTE: If no "template" folder is found, then default templates are loaded named "default" and "image". You probably don't want these because they are
 extremely basic, but it's okay for just messing around and viewing Markdown locally.
 
@@ -37,14 +43,22 @@ Front matter may include:
 	Name         | Type             | Description
 	-------------|------------------|------------------------------------------
 	title        | string           | Title of page
+	description  | string           | Short description of page
 	date         | time             | Publish date
-	tags         | array of strings | Tags for the articles (not used yet)
+	tags         | array of strings | Tags for the articles, used to find related pages
 	template     | string           | Override the template to render this file
 	redirect     | duration         | Provide redirect info (not automated)
 	originalfile | string           | Name of the base Markdown or image file
+	series       | string           | Name of a series of articles the page belongs to
+	weight       | int              | Ordering of the page in a series
+	templated    | bool             | Run the Markdown through the template engine before rendering it
+	bundle       | bool             | In index.md, makes the folder a page bundle
 
 Front matter is used for sorting and constructing lists of articles.
 
+A hidden ".defaults.toml" file in a folder holds front matter defaults for the Markdown files in that folder and its
+subfolders. Defaults from deeper folders override those from their parents, and a page's own front matter overrides them all.
+
 # Templates
 
 whisper uses standard Go templates from the "html/template" package. Templates are passed the following data:
@@ -52,24 +66,35 @@ whisper uses standard Go templates from the "html/template" package. Templates a
 	// FrontMatter holds data scraped from a Markdown page.
 	type FrontMatter struct {
 	    Title        string    `toml:"title"`        // Title of this page
+	    Description  string    `toml:"description"`  // Short description of this page
 	    Date         time.Time `toml:"date"`         // Date the article appears
 	    Template     string    `toml:"template"`     // The name of the template to use
 	    Tags         []string  `toml:"tags"`         // Tags to assign to this article
 	    Redirect     string    `toml:"redirect"`     // Issue a redirect to another location
 	    OriginalFile string    `toml:"originalfile"` // The original file (markdown or image)
+	    Series       string    `toml:"series"`       // Name of a series of articles this page belongs to
+	    Weight       int       `toml:"weight"`       // Ordering of this page in a series or menu
+	    Templated    bool      `toml:"templated"`    // Run the Markdown through the template engine first
+	    Bundle       bool      `toml:"bundle"`       // The folder of this index page is a page bundle
 	}
 
 	// PageInfo has information about the current page.
 	type PageInfo struct {
-	    Path     string // path from URL
-	    Filename string // end portion (file) from URL
+	    Path        string // path from URL
+	    Filename    string // end portion (file) from URL
+	    Number      int    // page number for paginated listings, starting at 1
+	    Breadcrumbs []File // index pages of the folders leading to this page, starting at the root
 	}
 
 	// data is what is passed to markdown templates.
 	type data struct {
-	    FrontMatter FrontMatter   // front matter from Markdown file or defaults
-	    Page        PageInfo      // information aboout current page
-	    Content     template.HTML // rendered Markdown
+	    FrontMatter  FrontMatter    // front matter from Markdown file or defaults
+	    Page         PageInfo       // information aboout current page
+	    Content      template.HTML  // rendered Markdown
+	    Media        MediaInfo      // metadata of audio and video files
+	    Language     string         // language of the page, if languages are configured
+	    Translations []Translation  // versions of the page in each language
+	    Site         map[string]any // site-wide variables from the [site] table in whisper.cfg
 	}
 
 Page is information about the current page, and FrontMatter is the front-matter from the current Markdown file.
@@ -82,12 +107,18 @@ Functions are added to the template for your convenience.
 	dir(path string) []File           | Return the contents of the given folder, excluding special files and subfolders.
 	sortbyname([]File) []File         | Sort by name (reverse)
 	sortbytime([]File) []File         | Sort by time (reverse)
+	sortbyweight([]File) []File       | Sort by weight, then time (reverse)
 	match(string, ...string) bool     | Match string against file patterns
 	filter([]File, ...string) []File  | Filter list against file patterns
 	join(parts ...string) string      | The same as path.Join
 	ext(path string) string           | The same as path.Ext
-	prev([]File, string) *File        | Find the previous file based on Filename
-	next([]File, string) *File        | Find the next file based on Filename
+	prev([]File, string) *File        | Find the previous file based on Filename or full path
+	next([]File, string) *File        | Find the next file based on Filename or full path
+	paginate(PageInfo, []File, int) *Pager | Split the list into pages and return the current page
+	series(name string) []File        | Pages of the named series across the site, sorted by weight (reverse)
+	tree(path string) *Node           | Folder hierarchy starting at the given folder, sorted by weight (reverse) then title
+	related(path string, int) []File  | Pages sharing tags with the given page, most shared tags first
+	translations(path string) []Translation | Versions of the given page in each language
 	reverse([]File) []File            | Reverse the list
 	trimsuffix(string, string) string | The same as strings.TrimSuffix
 	trimprefix(string, string) string | The same as strings.TrimPrefix
@@ -95,6 +126,24 @@ Functions are added to the template for your convenience.
 	markdown(string) template.HTML    | Render Markdown file into HTML
 	frontmatter(string) *FrontMatter  | Read front matter from file
 	now() time.Time                   | Current time
+	date(layout string, time.Time, zone ...string) string | Format the time with a layout like "Jan 2, 2006" or a name like "rfc3339", in an optional time zone
+	wordcount(text) int               | Number of words in text or HTML
+	readingtime(text) int             | Estimated minutes to read text or HTML
+	truncatehtml(int, html) template.HTML | Shorten HTML to about n characters of text, closing open tags
+	summary(html) template.HTML       | HTML before a "<!--more-->" comment, or else the first paragraph
+	slugify(string) string            | Convert text to a URL-friendly form like "hello-world"
+	where([]File, field string, value) []File | Files whose front matter field (or any element of a list like tags) matches the value
+	sortby([]File, field string, order ...string) []File | Sort a copy by a front matter field, ascending unless the order is "desc"
+	first(list) any                   | First item of a list
+	last(list) any                    | Last item of a list
+	limit(int, list) list             | At most the first n items of a list
+	add, sub, mul, div, mod(int, int) int | Integer math
+	dict(key, value, ...) map[string]any | Make a map, such as to pass several values to a template
+	slice(items ...) []any            | Make a list; this replaces the built-in slice function
+	absurl(path string) string        | Absolute URL of the path using the baseurl setting
+	resources(path string) []File     | Files of the page bundle in the given folder, other than Markdown
+	asset(path string) Asset          | Fingerprinted URL and integrity hash of a static file, minifying CSS and JavaScript
+	data(name string) any             | Contents of a file in the data folder, like data "menu" for data/menu.toml
 
 File is defined as:
 
@@ -102,6 +151,7 @@ File is defined as:
 	type File struct {
 	    FrontMatter FrontMatter
 	    Filename    string
+	    Path        string // folder holding the file, like "/articles/"
 	}
 
 If File is not a Markdown file, then FrontMatter.Title is set to the file name and FrontMatter.Date is set to the modification
@@ -110,10 +160,174 @@ time. The array is sorted by reverse date (most recent items first).
 Note that FrontMatter.OriginalFile is very useful because, for image templates, it will hold the name of the image file. You probably
 want to use it in the template.
 
+# Languages
+
+List the languages of the site in whisper.cfg, starting with the default, like languages = ["en", "es"]. A Markdown file
+named for a language, like "about.es.md", is a translation of "about.md" served as "/es/about.html". Visitors preferring
+another language via Accept-Language are served the translation when there is one. Templates can use Language and
+Translations, and sitemaps can call translations to list hreflang alternatives.
+
+# Templated Markdown
+
+Set "templated = true" in the front matter to run the Markdown through the template engine before it is rendered,
+using the same data and functions as other templates. This lets an "index.md" list its own folder. A page may read its
+own frontmatter. Pages included with markdown are nested at most four deep, so a page that includes itself stops there.
+
+# Shortcodes
+
+Markdown can call templates named "shortcodes/<name>" using shortcodes like {{< figure src="dog.png" >}}, optionally
+enclosing Markdown up to a closing {{< /name >}}. The template receives the Name, Params, Args, rendered Inner content,
+FrontMatter, and Page. A shortcode written as a comment, starting with "{{</*", is shown without running it, also on
+templated pages.
+
+# Site Variables
+
+A [site] table in whisper.cfg defines site-wide variables with any keys, available as .Site in every template, like
+{{.Site.title}}. Site maps receive a list of file names instead, so they call site, like {{site.title}}, and can use absurl.
+
+# Page Bundles
+
+Set "bundle = true" in a folder's index.md to make the folder a page bundle. Media files in a bundle are served as-is rather
+than getting pages of their own, dir lists the bundle like a page with a Filename like "trip/", and resources lists its files.
+
+# Assets
+
+Use asset to link to static files by a content-hashed name, like {{with asset "/static/site.css"}}{{.URL}}{{end}}, which
+gives "/static/site.0123456789abcdef.css" along with a sha384 Integrity hash. CSS and JavaScript are conservatively minified.
+Fingerprinted names are served with an immutable Cache-Control header that lasts a year instead of the staticexpires setting.
+Requests for an older fingerprint, like those from a cached page, are redirected to the current one.
+
+# Navigation
+
+Use tree to build menus from the folders of the site. Each Node embeds the File for the folder's index page, with the title
+and weight from its index.md front matter, and holds its subfolders in Children, sorted by weight in reverse like
+sortbyweight and then title. Page.Breadcrumbs lists the index pages of
+the folders leading to the current page, starting at the root.
+
+# Pagination
+
+Use paginate to split long listings. Later pages are served from URLs like "/articles/page/2.html", which render the
+listing's Markdown with Page.Number set. The returned Pager has the Items on the current page, the page Number and
+TotalPages, and URLs for the First, Last, Prev, and Next pages. Pages past the last one are not found.
+
 # Image Templates
 
-Folders named "photos", "images", "pictures", "cartoons", "toons", "sketches", "artwork", "drawings", "videos", or "movies" use a special handler that can
-serve media using an HTML template called "image" or "video".
+Folders named "photos", "images", "pictures", "cartoons", "toons", "sketches", "artwork", "drawings", "videos", "movies", "audio", "music", or "podcasts"
+use a special handler that can serve media using an HTML template called "image", "video", or "audio". Audio templates receive the duration and tags
+of the file in Media.
+
+Video and audio templates also receive the duration and size of the file, and the name of an image with the same base name
+(like "clip.jpg" for "clip.mp4") in Media.Poster, which can be used as a poster frame or cover art.
+
+Files larger than the "streamsize" setting (4MB by default) are streamed directly from disk, with support for Range requests,
+instead of being loaded into the cache.
+
+Other responses carry an ETag made from a hash of their content, as kept in the cache. Rendered pages carry a
+Last-Modified time that is the latest change to their source file, the templates and data files, and the folder they
+are in, which they can list. The site map carries the latest change to the whole site. Browsers and proxies can
+revalidate responses with If-None-Match or If-Modified-Since and get a 304 Not Modified response. Changes to other
+folders, shown through functions like tree and related, and the output of now are not tracked.
+
+Responses are compressed with Brotli, Zstandard, or gzip, depending on the Accept-Encoding header of the request.
+Precompressed files next to static files, like "site.css.br" or "site.css.gz", are served when present. Otherwise
+the compressed variant is made once and kept in the cache along with the rendered page. Only text, scripts, JSON, XML,
+and SVG images are compressed, so photos, video, and other binary files are served as they are.
+
+A media folder holding audio files also serves a generated podcast feed called "feed.xml". Set "baseurl" in whisper.cfg so that the feed uses absolute URLs;
+a warning is logged without it.
+
+# HTTPS
+
+By default whisper serves plain HTTP on -port and expects a proxy in front of it to handle TLS. To serve HTTPS directly,
+pass a PEM certificate and key:
+
+	whisper -root mysite -tlscert cert.pem -tlskey key.pem -tlsport 443 -port 80 -hsts 8760h
+
+HTTPS is served on -tlsport with HTTP/2, which can be tuned using -maxstreams and -pingtimeout. Requests to -port
+are redirected to HTTPS, unless -redirect=false is given. Redirects leave out the port, since -tlsport is often mapped
+to 443 by a load balancer or container; give the public port with -httpsport if it is not 443. Metrics and probes are
+still answered on -port without a redirect, unless -adminport is given. The files are checked every -certreload (one minute by default),
+so renewed certificates are picked up without a restart. The -hsts flag adds a Strict-Transport-Security header with the
+given max-age, unless the headers in whisper.cfg already set one.
+
+# Metrics
+
+Metrics are served in the Prometheus text format at /metrics, or on a separate port given by -adminport to keep them private.
+They include histograms of request durations by status code and content type, and of rendering time by kind of page,
+counts of template reloads by result, and statistics of the cache.
+
+# Health Checks
+
+These endpoints are meant for probes, like those of Kubernetes. They are served along with metrics, and hide any files
+with the same names.
+
+	/healthz  responds with 200 OK while the server is running
+	/readyz   responds with 200 OK once the site is ready, or 503 Service Unavailable with the reason
+	/version  responds with build information as JSON
+
+The site is not ready while waiting for files with -wait and loading the templates. A later template reload that fails is
+logged and counted in the metrics, and the previous templates are kept, so the site stays ready. With -adminport,
+the endpoints are served on that port from the start, so that readiness can be checked while waiting for files.
+
+# Access Log
+
+Requests are logged to standard output when an [accesslog] table in whisper.cfg selects a format, or when the -accesslog flag does:
+
+	[accesslog]
+	format = "json"                   # "text" or "json" for slog records, or "combined" for the Combined Log Format
+	sample = 0.1                      # log a tenth of requests; server errors are always logged
+	exclude = ["/static/", "/*.ico"]  # a folder, or a pattern for path.Match
+
+Each record has the method, path, status, bytes, duration, referer, user agent, and whether the response came from
+the cache ("hit" or "miss"). Large files streamed from disk have no cache status. The Combined Log Format adds the
+duration in seconds and the cache status to the end of each line.
+
+# Headers
+
+The [headers] table in whisper.cfg adds headers to every response. A [[headerrules]] list adds or overrides headers
+for some paths or content types. It can't be called [[headers]], because TOML does not allow a table and a list with
+the same name, and whisper reports an error naming [[headerrules]] if it is written that way. A rule matches a path, using the syntax of redirects, a type, like "text/html" or "image/*", or both:
+
+	[[headerrules]]
+	path = "/videos/*"
+	[headerrules.values]
+	Content-Security-Policy = "default-src 'self'; frame-src https://www.youtube.com"
+
+	[[headerrules]]
+	type = "image/*"
+	[headerrules.values]
+	X-Frame-Options = ""              # an empty value removes the header
+
+Matching rules are applied in order after the global headers, when the response is written and its type is known,
+so that they override headers like Cache-Control that whisper sets itself.
+
+# Redirects
+
+Pages that have moved can be redirected with a [[redirects]] list in whisper.cfg, instead of leaving a page with
+a redirect in its front matter. The rules are checked in order before serving files:
+
+	[[redirects]]
+	from = "/news/*"                  # a trailing * matches the rest of the path
+	to = "/blog/:splat"               # which is used as :splat
+	status = 302                      # 301 if not given
+
+	[[redirects]]
+	from = "/posts/:year/:slug"       # :name matches one segment of the path
+	to = "https://example.com/:year/:slug"
+
+A status of 200 rewrites the path internally, so the visitor sees the content of the target at the original URL.
+The query string is kept unless the target has its own. Rules also apply when a file exists at the path.
+
+Rules can also be kept in a Netlify-style "_redirects" file at the root, which is hidden from view. Its rules are
+checked after those in whisper.cfg, one per line, like "/news/* /blog/:splat 302".
+
+# Reloading
+
+whisper.cfg and _redirects are checked for changes every -configreload (ten seconds by default, 0 to only reload
+on signal), and are reloaded right away when the server receives SIGHUP. The new settings are validated first.
+An invalid config is logged and rejected, and the server keeps using the previous one. Otherwise each changed
+setting is logged, and the headers, expirations, languages, stream size, access log, and redirects take effect
+for the next request. Changing cachesize or cacheduration requires a restart.
 
 # Non-Goals
 
@@ -121,7 +335,7 @@ It's not a goal to make templates reusable. I expect templates need editing for
 
 It's not a goal to automate creation of the menu.
 
-It's not a goal to be a fully-featured server. I run https://caddyserver.com/ in front of it.
+It's not a goal to be a fully-featured server. HTTPS is built in for simple setups, but I run https://caddyserver.com/ in front of it.
 
 # More Detail
 
@@ -140,10 +354,10 @@ import (
 	"os/signal"
 	"path"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/NYTimes/gziphandler"
 	"github.com/ancientlore/flagenv"
 	"github.com/ancientlore/whisper/cachefs"
 	"github.com/ancientlore/whisper/virtual"
@@ -163,11 +377,24 @@ func main() {
 		fRoot              = flag.String("root", ".", "Root of web site.")
 		fCacheSize         = flag.Int("cachesize", 0, "Cache size in MB.")
 		fCacheDuration     = flag.Duration("cacheduration", 0, "How long to cache content.")
+		fStreamSize        = flag.Int("streamsize", 0, "Size in MB at which files are streamed instead of cached.")
 		fTemplateReload    = flag.Duration("templatereload", 10*time.Minute, "How often to reload templates.")
 		fExpires           = flag.Duration("expires", 0, "Default cache-control max-age header.")
 		fStaticExpires     = flag.Duration("staticexpires", 0, "Default cache-control max-age header for static content.")
 		fWaitForFiles      = flag.Bool("wait", false, "Wait for files to appear in root folder before starting up.")
 		fLogger            = flag.String("logger", "", "Select JSON or text logger.")
+		fTLSCert           = flag.String("tlscert", "", "TLS certificate file, which enables HTTPS.")
+		fTLSKey            = flag.String("tlskey", "", "TLS private key file.")
+		fTLSPort           = flag.Int("tlsport", 8443, "Port to listen on for HTTPS.")
+		fRedirect          = flag.Bool("redirect", true, "Redirect HTTP requests to HTTPS when TLS is enabled.")
+		fHTTPSPort         = flag.Int("httpsport", 443, "Public HTTPS port used when redirecting, if it differs from 443.")
+		fCertReload        = flag.Duration("certreload", time.Minute, "How often to check for a renewed TLS certificate.")
+		fHSTS              = flag.Duration("hsts", 0, "Strict-Transport-Security max-age when TLS is enabled.")
+		fMaxStreams        = flag.Int("maxstreams", 0, "Maximum concurrent HTTP/2 streams per connection.")
+		fPingTimeout       = flag.Duration("pingtimeout", 0, "Idle time after which HTTP/2 connections are checked with a ping.")
+		fAdminPort         = flag.Int("adminport", 0, "Port for metrics and probes, or 0 to serve them on the main port.")
+		fAccessLog         = flag.String("accesslog", "", "Access log format: text, json, or combined.")
+		fConfigReload      = flag.Duration("configreload", 10*time.Second, "How often to check whisper.cfg for changes.")
 	)
 	flag.Parse()
 	flagenv.Parse("")
@@ -184,6 +411,41 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Create the endpoints for metrics and probes. With an admin port, they are
+	// served right away, so that readiness can be checked while starting up.
+	var ready atomic.Bool
+	metrics := web.NewMetrics("whisper")
+	endpoints := map[string]http.Handler{
+		"/metrics": metrics,
+		"/healthz": web.HealthHandler(),
+		"/readyz": web.ReadyHandler(func() error {
+			// Later template reloads that fail are logged and counted, but
+			// the previous templates are kept, so the site stays ready.
+			if !ready.Load() {
+				return errors.New("starting up")
+			}
+			return nil
+		}),
+		"/version": web.VersionHandler(),
+	}
+	var adminSrv *http.Server
+	if *fAdminPort != 0 {
+		adminSrv = &http.Server{
+			Addr:              fmt.Sprintf(":%d", *fAdminPort),
+			ReadTimeout:       *fReadTimeout,
+			WriteTimeout:      *fWriteTimeout,
+			ReadHeaderTimeout: *fReadHeaderTimeout,
+			IdleTimeout:       *fIdleTimeout,
+			Handler:           web.EndpointHandler(http.NotFoundHandler(), endpoints),
+		}
+		go func() {
+			slog.Info("Listening for admin requests", "addr", adminSrv.Addr)
+			if err := adminSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Admin server", "error", err)
+			}
+		}()
+	}
+
 	// If requested, wait for files to show up in root folder, up to 60 seconds
 	if *fWaitForFiles {
 		err := waitForFiles(*fRoot)
@@ -212,52 +474,186 @@ func main() {
 		os.Exit(4)
 	}
 	defer virtualFileSystem.Close()
+	virtualFileSystem.Observe(metrics)
 	virtualFileSystem.ReloadTemplates(*fTemplateReload)
 
+	// loadConfig reads whisper.cfg, applying the flags that override it
+	useTLS := *fTLSCert != "" || *fTLSKey != ""
+	loadConfig := func() (*virtual.Config, error) {
+		cfg, err := virtualFileSystem.Config()
+		if err != nil {
+			return nil, err
+		}
+		if *fExpires != 0 {
+			cfg.Expires = virtual.Duration(*fExpires)
+		}
+		if *fStaticExpires != 0 {
+			cfg.StaticExpires = virtual.Duration(*fStaticExpires)
+		}
+		if *fCacheSize != 0 {
+			cfg.CacheSize = *fCacheSize
+		}
+		if *fCacheDuration != 0 {
+			cfg.CacheDuration = virtual.Duration(*fCacheDuration)
+		}
+		if *fStreamSize != 0 {
+			cfg.StreamSize = *fStreamSize
+		}
+		if *fAccessLog != "" {
+			cfg.AccessLog.Format = *fAccessLog
+		}
+		if cfg.CacheSize <= 0 {
+			cfg.CacheSize = 1 // need a default
+		}
+		if cfg.StreamSize <= 0 {
+			cfg.StreamSize = 4 // need a default
+		}
+		// Tell browsers to use HTTPS, unless whisper.cfg already does
+		if useTLS && *fHSTS > 0 {
+			hasHSTS := false
+			for k := range cfg.Headers {
+				hasHSTS = hasHSTS || http.CanonicalHeaderKey(k) == "Strict-Transport-Security"
+			}
+			if !hasHSTS {
+				if cfg.Headers == nil {
+					cfg.Headers = make(map[string]string)
+				}
+				cfg.Headers["Strict-Transport-Security"] = fmt.Sprintf("max-age=%d", int64(fHSTS.Seconds()))
+			}
+		}
+		return cfg, cfg.Validate()
+	}
+
 	// get the config
-	cfg, err := virtualFileSystem.Config()
+	cfg, err := loadConfig()
 	if err != nil {
 		slog.Error("Cannot load config", "error", err)
 		os.Exit(5)
 	}
-
-	// Apply config overrides
-	if *fExpires != 0 {
-		cfg.Expires = virtual.Duration(*fExpires)
-	}
-	if *fStaticExpires != 0 {
-		cfg.StaticExpires = virtual.Duration(*fStaticExpires)
-	}
-	if *fCacheSize != 0 {
-		cfg.CacheSize = *fCacheSize
-	}
-	if *fCacheDuration != 0 {
-		cfg.CacheDuration = virtual.Duration(*fCacheDuration)
-	}
-	if cfg.CacheSize <= 0 {
-		cfg.CacheSize = 1 // need a default
-	}
 	slog.Info("Expirations", "normal", cfg.Expires, "static", cfg.StaticExpires)
-	slog.Info("Cache", "size", fmt.Sprintf("%dMB", cfg.CacheSize), "duration", cfg.CacheDuration.String())
-
-	// Create the cached file system
-	cachedFileSystem := cachefs.New(virtualFileSystem, &cachefs.Config{GroupName: "whisper", SizeInBytes: int64(cfg.CacheSize) * 1024 * 1024, Duration: time.Duration(cfg.CacheDuration)})
-
-	// create handler
-	handler := web.HeaderHandler(
-		web.ExpiresHandler(
-			gziphandler.GzipHandler(
-				web.ErrorHandler(
-					http.FileServer(
-						http.FS(cachedFileSystem),
+	slog.Info("Cache", "size", fmt.Sprintf("%dMB", cfg.CacheSize), "duration", cfg.CacheDuration.String(), "streamsize", fmt.Sprintf("%dMB", cfg.StreamSize))
+	if cfg.AccessLog.Format != "" {
+		slog.Info("Access log", "format", cfg.AccessLog.Format, "sample", cfg.AccessLog.Sample, "exclude", cfg.AccessLog.Exclude)
+	}
+
+	// Create the cached file system, tracking loads to tell cache hits from misses
+	loadTracker := web.TrackLoads(virtualFileSystem)
+	// NoStat keeps the cache from rendering every page of a folder to find their sizes when it caches the folder
+	cachedFileSystem := cachefs.New(loadTracker, &cachefs.Config{GroupName: "whisper", SizeInBytes: int64(cfg.CacheSize) * 1024 * 1024, Duration: time.Duration(cfg.CacheDuration), NoStat: true})
+
+	// newHandler creates the handler for the site using the given config
+	newHandler := func(cfg *virtual.Config) http.Handler {
+		redirects := make([]web.Redirect, len(cfg.Redirects))
+		for i, r := range cfg.Redirects {
+			redirects[i] = web.Redirect{From: r.From, To: r.To, Status: r.Status}
+		}
+		headerRules := make([]web.HeaderRule, len(cfg.HeaderRules))
+		for i, r := range cfg.HeaderRules {
+			headerRules[i] = web.HeaderRule{Path: r.Path, Type: r.Type, Values: r.Values}
+		}
+		handler := web.HeaderHandler(
+			web.RedirectHandler(
+				web.ExpiresHandler(
+					web.AssetRedirectHandler(
+						web.StreamHandler(
+							web.LanguageHandler(
+								web.CacheStatusHandler(
+									web.ETagHandler(
+										web.CompressHandler(
+											web.ErrorHandler(
+												http.FileServer(
+													http.FS(cachedFileSystem),
+												),
+												cachedFileSystem,
+											),
+											cachedFileSystem,
+										),
+										cachedFileSystem,
+									),
+									loadTracker,
+								),
+								cachedFileSystem,
+								cfg.Languages,
+							),
+							virtualFileSystem,
+							int64(cfg.StreamSize)*1024*1024,
+						),
+						cachedFileSystem,
+						virtualFileSystem.CurrentAsset,
 					),
-					cachedFileSystem,
+					time.Duration(cfg.Expires),
+					time.Duration(cfg.StaticExpires),
 				),
+				redirects,
 			),
-			time.Duration(cfg.Expires),
-			time.Duration(cfg.StaticExpires),
-		),
-		cfg.Headers)
+			cfg.Headers,
+			headerRules,
+		)
+		if cfg.AccessLog.Format != "" {
+			handler = web.AccessLogHandler(handler, os.Stdout, cfg.AccessLog.Format, cfg.AccessLog.Sample, cfg.AccessLog.Exclude)
+		}
+		return handler
+	}
+
+	// create handler, which is replaced when the config changes
+	var siteHandler atomic.Pointer[http.Handler]
+	h := newHandler(cfg)
+	siteHandler.Store(&h)
+	handler := web.MetricsHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		(*siteHandler.Load()).ServeHTTP(w, r)
+	}), metrics)
+
+	// Without an admin port, serve metrics and probes along with the site
+	if adminSrv == nil {
+		handler = web.EndpointHandler(handler, endpoints)
+	}
+
+	// Reload the config when whisper.cfg changes or on SIGHUP
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		var tick <-chan time.Time
+		if *fConfigReload > 0 {
+			t := time.NewTicker(*fConfigReload)
+			defer t.Stop()
+			tick = t.C
+		}
+		current := cfg
+		modTime := configModTime(root)
+		for {
+			select {
+			case <-hup:
+				slog.Info("Reloading on SIGHUP")
+			case <-tick:
+				if configModTime(root).Equal(modTime) {
+					continue
+				}
+				slog.Info("Reloading changed config")
+			}
+			modTime = configModTime(root)
+			if err := virtualFileSystem.Reload(); err != nil {
+				slog.Error("Failed to load templates", "error", err)
+			}
+			newCfg, err := loadConfig()
+			if err != nil {
+				slog.Error("Rejected config; keeping the previous one", "error", err)
+				continue
+			}
+			changes := current.Diff(newCfg)
+			for _, c := range changes {
+				if c.Name == "cachesize" || c.Name == "cacheduration" {
+					slog.Warn("Config changed; restart to apply", "setting", c.Name, "old", c.Old, "new", c.New)
+				} else {
+					slog.Info("Config changed", "setting", c.Name, "old", c.Old, "new", c.New)
+				}
+			}
+			if len(changes) > 0 {
+				h := newHandler(newCfg)
+				siteHandler.Store(&h)
+				current = newCfg
+			}
+		}
+	}()
 
 	// Create HTTP server
 	var srv = http.Server{
@@ -267,6 +663,39 @@ func main() {
 		ReadHeaderTimeout: *fReadHeaderTimeout,
 		IdleTimeout:       *fIdleTimeout,
 		Handler:           handler,
+		HTTP2: &http.HTTP2Config{
+			MaxConcurrentStreams: *fMaxStreams,
+			SendPingTimeout:      *fPingTimeout,
+		},
+	}
+
+	// With TLS, serve HTTPS and redirect plain HTTP to it
+	var httpSrv *http.Server
+	if useTLS {
+		cert, err := web.LoadCertificate(*fTLSCert, *fTLSKey)
+		if err != nil {
+			slog.Error("Unable to load TLS certificate", "error", err)
+			os.Exit(6)
+		}
+		defer cert.Close()
+		cert.Reload(*fCertReload)
+		srv.Addr = fmt.Sprintf(":%d", *fTLSPort)
+		srv.TLSConfig = web.TLSConfig(cert)
+		httpSrv = &http.Server{
+			Addr:              fmt.Sprintf(":%d", *fPort),
+			ReadTimeout:       *fReadTimeout,
+			WriteTimeout:      *fWriteTimeout,
+			ReadHeaderTimeout: *fReadHeaderTimeout,
+			IdleTimeout:       *fIdleTimeout,
+			Handler:           handler,
+		}
+		if *fRedirect {
+			httpSrv.Handler = web.HTTPSRedirectHandler(*fHTTPSPort)
+			// probes may not follow the redirect
+			if adminSrv == nil {
+				httpSrv.Handler = web.EndpointHandler(httpSrv.Handler, endpoints)
+			}
+		}
 	}
 
 	// Start cache monitor
@@ -290,12 +719,32 @@ func main() {
 			// Error from closing listeners, or context timeout:
 			slog.Error("HTTP server Shutdown", "error", err)
 		}
+		for _, other := range []*http.Server{httpSrv, adminSrv} {
+			if other != nil {
+				if err := other.Shutdown(ctx); err != nil {
+					slog.Error("HTTP server Shutdown", "error", err)
+				}
+			}
+		}
 		close(monc)
 	}()
 
 	// Listen for requests
-	slog.Info("Listening for requests")
-	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+	ready.Store(true)
+	if httpSrv != nil {
+		go func() {
+			slog.Info("Listening for HTTP requests", "addr", httpSrv.Addr)
+			if err := httpSrv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("HTTP server", "error", err)
+			}
+		}()
+		slog.Info("Listening for HTTPS requests", "addr", srv.Addr)
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		slog.Info("Listening for requests")
+		err = srv.ListenAndServe()
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
 		slog.Error("HTTP server", "error", err)
 	} else {
 		slog.Info("Goodbye.")
@@ -337,6 +786,18 @@ func stats(groupName string) chan<- bool {
 	return c
 }
 
+// configModTime returns the latest modification time of whisper.cfg and _redirects,
+// or the zero time if neither exists.
+func configModTime(root *os.Root) time.Time {
+	var t time.Time
+	for _, name := range []string{"whisper.cfg", "_redirects"} {
+		if fi, err := root.Stat(name); err == nil && fi.ModTime().After(t) {
+			t = fi.ModTime()
+		}
+	}
+	return t
+}
+
 func waitForFiles(pathname string) error {
 	foundFiles := false
 	for i := 0; i < 60; i++ {
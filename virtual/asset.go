@@ -0,0 +1,263 @@
+package virtual
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Asset is a fingerprinted static file returned by the asset template function.
+type Asset struct {
+	URL       string // fingerprinted URL, like "/static/site.0123456789abcdef.css"
+	Integrity string // subresource integrity hash, like "sha384-..."
+}
+
+// fingerprintLen is the number of hex digits of the content hash used in asset names.
+const fingerprintLen = 16
+
+// fingerprintRegexp matches fingerprinted names like "site.0123456789abcdef.css".
+var fingerprintRegexp = regexp.MustCompile(`^(.+)\.([0-9a-f]{16})(\.[^./]+)$`)
+
+// assetContent reads the named file, minifying CSS and JavaScript.
+func (vfs *FS) assetContent(name string) ([]byte, fs.FileInfo, error) {
+	fi, err := fs.Stat(vfs.fs, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, nil, fs.ErrNotExist
+	}
+	b, err := fs.ReadFile(vfs.fs, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch path.Ext(name) {
+	case ".css":
+		b = minifyCSS(b)
+	case ".js":
+		b = minifyJS(b)
+	}
+	return b, fi, nil
+}
+
+// fingerprint returns the hex digits of the content hash used in asset names.
+func fingerprint(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])[:fingerprintLen]
+}
+
+// asset returns the fingerprinted URL and integrity hash of the given static file and is
+// used in templates. CSS and JavaScript files are minified.
+func (vfs *FS) asset(pathname string) (Asset, error) {
+	name := strings.TrimPrefix(path.Clean("/"+pathname), "/")
+	if isHiddenFile(name) || containsSpecialFile(name) {
+		return Asset{}, fmt.Errorf("asset: %s: %w", pathname, fs.ErrNotExist)
+	}
+	b, _, err := vfs.assetContent(name)
+	if err != nil {
+		return Asset{}, fmt.Errorf("asset: %w", err)
+	}
+	ext := path.Ext(name)
+	sum := sha512.Sum384(b)
+	return Asset{
+		URL:       "/" + strings.TrimSuffix(name, ext) + "." + fingerprint(b) + ext,
+		Integrity: "sha384-" + base64.StdEncoding.EncodeToString(sum[:]),
+	}, nil
+}
+
+// CurrentAsset returns the current fingerprinted URL of a fingerprinted path like
+// "/static/site.0123456789abcdef.css", whose content may have changed since.
+func (vfs *FS) CurrentAsset(pathname string) (string, error) {
+	m := fingerprintRegexp.FindStringSubmatch(strings.TrimPrefix(pathname, "/"))
+	if m == nil {
+		return "", &fs.PathError{Op: "asset", Path: pathname, Err: fs.ErrNotExist}
+	}
+	a, err := vfs.asset(m[1] + m[3])
+	if err != nil {
+		return "", err
+	}
+	return a.URL, nil
+}
+
+// newAssetFile returns the content of a fingerprinted asset like "static/site.0123456789abcdef.css",
+// or fs.ErrNotExist if there is no such file or its content has changed.
+func (vfs *FS) newAssetFile(pathname string) (fs.File, error) {
+	m := fingerprintRegexp.FindStringSubmatch(pathname)
+	if m == nil {
+		return nil, &fs.PathError{Op: "open", Path: pathname, Err: fs.ErrNotExist}
+	}
+	b, fi, err := vfs.assetContent(m[1] + m[3])
+	if err != nil || fingerprint(b) != m[2] {
+		return nil, &fs.PathError{Op: "open", Path: pathname, Err: fs.ErrNotExist}
+	}
+	return &virtualFile{
+		fi: fileInfo{
+			nm: path.Base(pathname),
+			sz: int64(len(b)),
+			md: fi.Mode(),
+			mt: fi.ModTime(),
+		},
+		reader: bytes.NewReader(b),
+	}, nil
+}
+
+// minifyCSS removes comments and unneeded whitespace from CSS, leaving strings alone.
+func minifyCSS(b []byte) []byte {
+	var out bytes.Buffer
+	space := false
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == '"' || c == '\'':
+			if space {
+				writeSpace(&out, c)
+				space = false
+			}
+			i = copyString(&out, b, i)
+		case c == '/' && i+1 < len(b) && b[i+1] == '*':
+			end := bytes.Index(b[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(b)
+			} else {
+				i += end + 3
+			}
+			space = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			space = true
+		default:
+			if c == '}' {
+				// the last semicolon in a block is optional
+				if n := out.Len(); n > 0 && out.Bytes()[n-1] == ';' {
+					out.Truncate(n - 1)
+				}
+			}
+			if space {
+				writeSpace(&out, c)
+				space = false
+			}
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
+
+// writeSpace writes a single space unless it is next to punctuation that does not need one.
+func writeSpace(out *bytes.Buffer, next byte) {
+	if out.Len() == 0 || strings.IndexByte("{};,>", next) >= 0 {
+		return
+	}
+	if prev := out.Bytes()[out.Len()-1]; strings.IndexByte("{};,>", prev) >= 0 {
+		return
+	}
+	out.WriteByte(' ')
+}
+
+// copyString copies the quoted string starting at b[i] to out, returning the index of the closing quote.
+func copyString(out *bytes.Buffer, b []byte, i int) int {
+	quote := b[i]
+	out.WriteByte(quote)
+	for i++; i < len(b); i++ {
+		out.WriteByte(b[i])
+		if b[i] == '\\' && i+1 < len(b) {
+			i++
+			out.WriteByte(b[i])
+		} else if b[i] == quote {
+			break
+		}
+	}
+	return i
+}
+
+// minifyJS conservatively minifies JavaScript by removing comments, indentation,
+// and blank lines. Line breaks are kept so that automatic semicolon insertion
+// still works, and strings and template literals are left alone.
+func minifyJS(b []byte) []byte {
+	var (
+		out  bytes.Buffer
+		line bytes.Buffer
+	)
+	flush := func() {
+		if s := bytes.TrimSpace(line.Bytes()); len(s) > 0 {
+			out.Write(s)
+			out.WriteByte('\n')
+		}
+		line.Reset()
+	}
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == '"' || c == '\'' || c == '`':
+			i = copyString(&line, b, i)
+		case c == '/' && i+1 < len(b) && b[i+1] == '/':
+			for i < len(b) && b[i] != '\n' {
+				i++
+			}
+			flush()
+		case c == '/' && i+1 < len(b) && b[i+1] == '*':
+			end := bytes.Index(b[i+2:], []byte("*/"))
+			if end < 0 {
+				end = len(b) - i - 2
+			}
+			// a comment spanning lines still separates statements
+			if bytes.IndexByte(b[i+2:i+2+end], '\n') >= 0 {
+				flush()
+			} else {
+				line.WriteByte(' ')
+			}
+			i += end + 3
+		case c == '/' && regexAllowed(out.Bytes(), line.Bytes()):
+			i = copyRegexp(&line, b, i)
+		case c == '\n':
+			flush()
+		default:
+			line.WriteByte(c)
+		}
+	}
+	flush()
+	return out.Bytes()
+}
+
+// regexAllowed reports whether a slash after the given output starts a regular
+// expression rather than a division, based on the last significant character.
+func regexAllowed(out, line []byte) bool {
+	prev := bytes.TrimRight(line, " \t")
+	if len(prev) == 0 {
+		prev = bytes.TrimRight(out, " \t\n")
+	}
+	if len(prev) == 0 {
+		return true
+	}
+	if bytes.HasSuffix(prev, []byte("return")) || bytes.HasSuffix(prev, []byte("typeof")) {
+		return true
+	}
+	return strings.IndexByte("(,=:[!&|?{};+-*%<>~^", prev[len(prev)-1]) >= 0
+}
+
+// copyRegexp copies the regular expression literal starting at b[i] to out,
+// returning the index of the closing slash.
+func copyRegexp(out *bytes.Buffer, b []byte, i int) int {
+	out.WriteByte(b[i])
+	class := false
+	for i++; i < len(b) && b[i] != '\n'; i++ {
+		out.WriteByte(b[i])
+		switch {
+		case b[i] == '\\' && i+1 < len(b):
+			i++
+			out.WriteByte(b[i])
+		case b[i] == '[':
+			class = true
+		case b[i] == ']':
+			class = false
+		case b[i] == '/' && !class:
+			return i
+		}
+	}
+	return i - 1
+}
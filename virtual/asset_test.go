@@ -0,0 +1,81 @@
+package virtual
+
+import (
+	"errors"
+	"html"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMinifyCSS(t *testing.T) {
+	in := `/* site styles */
+body ,  p {
+    font-family: "Open  Sans", sans-serif;
+    margin: 0 auto;
+}
+a > b { content: '  a;b  ' ; }
+`
+	expect := `body,p{font-family: "Open  Sans",sans-serif;margin: 0 auto}a>b{content: '  a;b  '}`
+	if s := string(minifyCSS([]byte(in))); s != expect {
+		t.Errorf("Expected %q but got %q", expect, s)
+	}
+}
+
+func TestMinifyJS(t *testing.T) {
+	in := `// greeting
+function hello(name) {
+    /* say it */
+    var s = "hello // " + name; // comment
+    var r = /["'\/]+/g;
+    return s.replace(r, '') / 2
+}
+var t = ` + "`a\n  b`" + `
+`
+	expect := "function hello(name) {\nvar s = \"hello // \" + name;\nvar r = /[\"'\\/]+/g;\nreturn s.replace(r, '') / 2\n}\nvar t = `a\n  b`\n"
+	if s := string(minifyJS([]byte(in))); s != expect {
+		t.Errorf("Expected %q but got %q", expect, s)
+	}
+}
+
+func TestAsset(t *testing.T) {
+	fileSys, err := New(fstest.MapFS{
+		"static/site.css":       {Data: []byte("body {\n  margin: 0;\n}\n")},
+		"template/default.html": {Data: []byte(`{{define "default"}}{{with asset "/static/site.css"}}<link href="{{.URL}}" integrity="{{.Integrity}}">{{end}}{{end}}`)},
+		"index.md":              {Data: []byte("# Home")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := fileSys.asset("/static/site.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fingerprintRegexp.MatchString(a.URL) || !strings.HasPrefix(a.URL, "/static/site.") || !strings.HasPrefix(a.Integrity, "sha384-") {
+		t.Errorf("Unexpected asset %#v", a)
+	}
+	b, err := fs.ReadFile(fileSys, strings.TrimPrefix(a.URL, "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "body{margin: 0}" {
+		t.Errorf("Expected minified CSS but got %q", b)
+	}
+	b, err = fs.ReadFile(fileSys, "index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := html.UnescapeString(string(b)); !strings.Contains(s, a.URL) || !strings.Contains(s, a.Integrity) {
+		t.Errorf("Expected asset in page but got %s", b)
+	}
+	if _, err = fs.Stat(fileSys, "static/site.0123456789abcdef.css"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a stale fingerprint to not exist: %v", err)
+	}
+	if u, err := fileSys.CurrentAsset("/static/site.0123456789abcdef.css"); err != nil || u != a.URL {
+		t.Errorf("Expected current asset %q but got %q: %v", a.URL, u, err)
+	}
+	if _, err = fileSys.asset("/whisper.cfg"); err == nil {
+		t.Errorf("Expected hidden files to not be assets")
+	}
+}
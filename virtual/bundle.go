@@ -0,0 +1,61 @@
+package virtual
+
+import (
+	"io/fs"
+	"log/slog"
+	"path"
+	"strings"
+)
+
+// isBundle returns true if the folder's index.md sets "bundle = true", making the
+// folder a page bundle whose media files are resources of the page rather than
+// pages of their own.
+func (vfs *FS) isBundle(folder string) bool {
+	var fm FrontMatter
+	err := vfs.readFrontMatter(path.Join(folder, "index.md"), &fm)
+	return err == nil && fm.Bundle
+}
+
+// bundleFile returns the File listing the page bundle in the given folder, with
+// the front matter of its index.md and a Filename like "trip/".
+func (vfs *FS) bundleFile(folder string, fm FrontMatter) File {
+	err := vfs.readFrontMatter(path.Join(folder, "index.md"), &fm)
+	if err != nil {
+		slog.Warn("bundleFile problem reading front matter", "error", err)
+	}
+	return File{FrontMatter: fm, Filename: path.Base(folder) + "/", Path: folderURL(path.Dir(folder))}
+}
+
+// resources returns the files of the page bundle in the given folder, excluding
+// Markdown and special files, and is used in templates.
+func (vfs *FS) resources(folderpath string) []File {
+	folderpath = path.Clean("./" + strings.TrimPrefix(folderpath, "/"))
+	if path.Ext(folderpath) == ".html" {
+		folderpath = path.Dir(folderpath)
+	}
+	if isHiddenFile(folderpath) || (folderpath != "." && containsSpecialFile(folderpath)) {
+		return nil
+	}
+	entries, err := fs.ReadDir(vfs.fs, folderpath)
+	if err != nil {
+		slog.Error("resources: ReadDir failed", "error", err)
+		return nil
+	}
+	folder := folderURL(folderpath)
+	var f []File
+	for _, entry := range entries {
+		nm := entry.Name()
+		if entry.IsDir() || containsSpecialFile(nm) || isHiddenFile(path.Join(folderpath, nm)) || path.Ext(nm) == ".md" {
+			continue
+		}
+		fm := FrontMatter{
+			Title:        strings.TrimSuffix(nm, path.Ext(nm)),
+			OriginalFile: nm,
+		}
+		if fi, err := entry.Info(); err == nil {
+			fm.Date = fi.ModTime().Local()
+		}
+		f = append(f, File{FrontMatter: fm, Filename: nm, Path: folder})
+	}
+	return f
+}
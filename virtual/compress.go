@@ -0,0 +1,89 @@
+package virtual
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// encoders create compressed variants of files, keyed by the extension of the variant.
+// Variants are made on each cache miss, so Brotli and Zstandard use moderate levels
+// that compress well without taking long.
+var encoders = map[string]func(io.Writer) (io.WriteCloser, error){
+	".br": func(w io.Writer) (io.WriteCloser, error) {
+		return brotli.NewWriterLevel(w, 5), nil
+	},
+	".gz": func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, gzip.BestCompression)
+	},
+	".zst": func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+	},
+}
+
+// compressibleExtensions are the extensions of text files worth compressing.
+var compressibleExtensions = []string{
+	".html", ".css", ".js", ".mjs", ".json", ".xml", ".txt", ".svg", ".csv", ".webmanifest",
+}
+
+// isCompressible returns true if the file is text that is worth compressing.
+func isCompressible(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, e := range compressibleExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// newCompressedFile returns a compressed variant like "articles/how.html.br" of a
+// text file, which may be a rendered page. It returns fs.ErrNotExist when the file
+// does not exist, is not compressible, or does not get any smaller.
+func (vfs *FS) newCompressedFile(pathname string) (fs.File, error) {
+	ext := path.Ext(pathname)
+	name := strings.TrimSuffix(pathname, ext)
+	encoder, ok := encoders[ext]
+	if !ok || !isCompressible(name) {
+		return nil, &fs.PathError{Op: "open", Path: pathname, Err: fs.ErrNotExist}
+	}
+	f, err := vfs.Open(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: pathname, Err: fs.ErrNotExist}
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		return nil, &fs.PathError{Op: "open", Path: pathname, Err: fs.ErrNotExist}
+	}
+	var buf bytes.Buffer
+	w, err := encoder(&buf)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: pathname, Err: err}
+	}
+	_, err = io.Copy(w, f)
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: pathname, Err: err}
+	}
+	if int64(buf.Len()) >= fi.Size() {
+		return nil, &fs.PathError{Op: "open", Path: pathname, Err: fs.ErrNotExist}
+	}
+	return &virtualFile{
+		fi: fileInfo{
+			nm: path.Base(pathname),
+			sz: int64(buf.Len()),
+			md: fi.Mode(),
+			mt: fi.ModTime(),
+		},
+		reader: bytes.NewReader(buf.Bytes()),
+	}, nil
+}
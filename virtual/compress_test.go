@@ -0,0 +1,70 @@
+package virtual
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompressedFiles(t *testing.T) {
+	page := strings.Repeat("All work and no play makes Jack a dull boy. ", 20)
+	fileSys, err := New(fstest.MapFS{
+		"template/default.html": {Data: []byte(`{{define "default"}}{{.Content}}{{end}}`)},
+		"index.md":              {Data: []byte(page)},
+		"static/site.css":       {Data: []byte(strings.Repeat("p { color: red; }\n", 20))},
+		"static/site.css.gz":    {Data: []byte("precompressed")},
+		"static/tiny.css":       {Data: []byte("p{}")},
+		"photos/cat.png":        {Data: []byte(strings.Repeat("png", 100))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	html, err := fs.ReadFile(fileSys, "index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		".br": func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		".gz": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		".zst": func(r io.Reader) (io.Reader, error) {
+			d, err := zstd.NewReader(r)
+			return d, err
+		},
+	}
+	for ext, decoder := range decoders {
+		b, err := fs.ReadFile(fileSys, "index.html"+ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) >= len(html) {
+			t.Errorf("Expected %s to be smaller than %d bytes but it is %d", ext, len(html), len(b))
+		}
+		r, err := decoder(bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(d, html) {
+			t.Errorf("Decompressed %s does not match the page", ext)
+		}
+	}
+	if b, err := fs.ReadFile(fileSys, "static/site.css.gz"); err != nil || string(b) != "precompressed" {
+		t.Errorf("Expected the precompressed file but got %q: %v", b, err)
+	}
+	for _, name := range []string{"static/tiny.css.br", "photos/cat.png.gz", "index.md.gz", "missing.html.gz"} {
+		if _, err := fs.Stat(fileSys, name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Expected %s not to exist: %v", name, err)
+		}
+	}
+}
@@ -1,37 +1,170 @@
 package virtual
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/fs"
+	"net/url"
+	"reflect"
+	"strings"
 
 	"github.com/pelletier/go-toml/v2"
 )
 
 // Config contains configuration data from the whisper.cfg file.
 type Config struct {
+	BaseURL       string            `toml:"baseurl"`       // Public URL of the site, used for absolute links
 	Expires       Duration          `toml:"expires"`       // Expiry duration for dynamic content
 	StaticExpires Duration          `toml:"staticexpires"` // Expiry duration for static content
 	CacheSize     int               `toml:"cachesize"`     // Cache size in megabytes
 	CacheDuration Duration          `toml:"cacheduration"` // Cache duration
+	Languages     []string          `toml:"languages"`     // Languages of the site, starting with the default
+	StreamSize    int               `toml:"streamsize"`    // Files of at least this many megabytes bypass the cache
 	Headers       map[string]string `toml:"headers"`       // Headers to add
+	Site          map[string]any    `toml:"site"`          // Site-wide variables for templates
+	AccessLog     AccessLog         `toml:"accesslog"`     // Access log settings
+	Redirects     []Redirect        `toml:"redirects"`     // Redirect and rewrite rules, checked in order
+	HeaderRules   []HeaderRule      `toml:"headerrules"`   // Headers to add for some paths or content types
 }
 
-// Config returns configuration from the whisper.cfg file.
-// It is not an error if the file does not exist.
+// AccessLog holds the settings of the [accesslog] table in the whisper.cfg file.
+type AccessLog struct {
+	Format  string   `toml:"format"`  // "text", "json", or "combined"; the access log is off if empty
+	Sample  float64  `toml:"sample"`  // Fraction of requests to log, or 0 to log them all
+	Exclude []string `toml:"exclude"` // Paths not to log, like "/static/" for a folder or "/*.ico"
+}
+
+// Redirect holds a rule of the [[redirects]] list in the whisper.cfg file.
+type Redirect struct {
+	From   string `toml:"from"`   // Path to match, like "/blog/:year/*"
+	To     string `toml:"to"`     // Path or URL to send to, like "/posts/:year/:splat"
+	Status int    `toml:"status"` // Redirect status code, 301 if zero, or 200 to rewrite the path internally
+}
+
+// HeaderRule holds a rule of the [[headerrules]] list in the whisper.cfg file.
+// The list can't be called [[headers]], because that is the table of headers
+// for every response. When both Path and Type are given, both must match.
+type HeaderRule struct {
+	Path   string            `toml:"path"`   // Path to match, like "/videos/*", using the syntax of redirects
+	Type   string            `toml:"type"`   // Content type to match, like "text/html" or "image/*"
+	Values map[string]string `toml:"values"` // Headers to set, overriding the global ones; an empty value removes the header
+}
+
+// Config returns configuration from the whisper.cfg file, adding the rules of
+// a _redirects file after any [[redirects]]. It is not an error if the files do not
+// exist, but it is if the settings are not valid.
 func (vfs *FS) Config() (*Config, error) {
 	var cfg Config
 	cfgBytes, err := fs.ReadFile(vfs.fs, "whisper.cfg")
 	if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			return &cfg, nil
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("cannot read config file: %w", err)
 		}
-		return nil, fmt.Errorf("cannot read config file: %w", err)
 	} else {
 		err = toml.Unmarshal(cfgBytes, &cfg)
 		if err != nil {
+			// [headers] is the table of global headers, so rules have another name
+			if bytes.Contains(cfgBytes, []byte("[[headers]]")) {
+				return nil, fmt.Errorf("cannot parse config file: use [[headerrules]] for a list of header rules, since [headers] is a table: %w", err)
+			}
 			return nil, fmt.Errorf("cannot parse config file: %w", err)
 		}
 	}
+	redirects, err := vfs.loadRedirects()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Redirects = append(cfg.Redirects, redirects...)
+	if err = cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
+
+// Validate checks that the settings make sense, so that a broken whisper.cfg
+// can be rejected rather than used.
+func (cfg *Config) Validate() error {
+	if cfg.BaseURL != "" {
+		u, err := url.Parse(cfg.BaseURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid config: baseurl %q is not an absolute URL", cfg.BaseURL)
+		}
+	}
+	if cfg.Expires < 0 || cfg.StaticExpires < 0 || cfg.CacheDuration < 0 {
+		return errors.New("invalid config: durations cannot be negative")
+	}
+	if cfg.CacheSize < 0 || cfg.StreamSize < 0 {
+		return errors.New("invalid config: sizes cannot be negative")
+	}
+	seen := make(map[string]bool)
+	for _, lang := range cfg.Languages {
+		if lang == "" || strings.Contains(lang, "/") || seen[lang] {
+			return fmt.Errorf("invalid config: bad or repeated language %q", lang)
+		}
+		seen[lang] = true
+	}
+	if err := validateHeaders(cfg.Headers); err != nil {
+		return err
+	}
+	switch cfg.AccessLog.Format {
+	case "", "text", "json", "combined":
+	default:
+		return fmt.Errorf("invalid config: access log format %q is not \"text\", \"json\", or \"combined\"", cfg.AccessLog.Format)
+	}
+	if cfg.AccessLog.Sample < 0 || cfg.AccessLog.Sample > 1 {
+		return fmt.Errorf("invalid config: access log sample %v is not between 0 and 1", cfg.AccessLog.Sample)
+	}
+	for _, r := range cfg.Redirects {
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("invalid config: %w", err)
+		}
+	}
+	for _, r := range cfg.HeaderRules {
+		if r.Path == "" && r.Type == "" {
+			return errors.New("invalid config: header rule needs a path or a type")
+		}
+		if r.Path != "" && !strings.HasPrefix(r.Path, "/") {
+			return fmt.Errorf("invalid config: header rule path %q must start with \"/\"", r.Path)
+		}
+		if r.Type != "" && !strings.Contains(r.Type, "/") {
+			return fmt.Errorf("invalid config: header rule type %q is not like \"text/html\"", r.Type)
+		}
+		if err := validateHeaders(r.Values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateHeaders checks that the header names are valid.
+func validateHeaders(headers map[string]string) error {
+	for k := range headers {
+		if k == "" || strings.ContainsAny(k, " \t\r\n:") {
+			return fmt.Errorf("invalid config: bad header name %q", k)
+		}
+	}
+	return nil
+}
+
+// ConfigChange describes a setting that differs between two configurations.
+type ConfigChange struct {
+	Name     string // Name of the setting in whisper.cfg, like "expires"
+	Old, New any    // Old and new values
+}
+
+// Diff returns the settings that differ in the newer configuration, in the
+// order of the Config fields.
+func (cfg *Config) Diff(newer *Config) []ConfigChange {
+	var changes []ConfigChange
+	a, b := reflect.ValueOf(cfg).Elem(), reflect.ValueOf(newer).Elem()
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		x, y := a.Field(i).Interface(), b.Field(i).Interface()
+		if !reflect.DeepEqual(x, y) {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ",")
+			changes = append(changes, ConfigChange{Name: name, Old: x, New: y})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,102 @@
+package virtual
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	good := Config{
+		BaseURL:   "https://example.com/",
+		Expires:   Duration(time.Minute),
+		Languages: []string{"en", "es"},
+		Headers:   map[string]string{"X-Frame-Options": "DENY"},
+		AccessLog: AccessLog{Format: "json", Sample: 0.5},
+		HeaderRules: []HeaderRule{
+			{Path: "/videos/*", Values: map[string]string{"Content-Security-Policy": ""}},
+			{Type: "image/*", Values: map[string]string{"X-Frame-Options": ""}},
+		},
+	}
+	if err := good.Validate(); err != nil {
+		t.Errorf("Expected valid config: %v", err)
+	}
+	bad := map[string]func(*Config){
+		"baseurl":   func(c *Config) { c.BaseURL = "example.com" },
+		"expires":   func(c *Config) { c.Expires = Duration(-time.Minute) },
+		"cachesize": func(c *Config) { c.CacheSize = -1 },
+		"languages": func(c *Config) { c.Languages = []string{"en", "en"} },
+		"headers":   func(c *Config) { c.Headers = map[string]string{"Bad Header": "x"} },
+		"format":    func(c *Config) { c.AccessLog.Format = "xml" },
+		"sample":    func(c *Config) { c.AccessLog.Sample = 2 },
+		"rule":      func(c *Config) { c.HeaderRules = []HeaderRule{{Values: map[string]string{"X-Frame-Options": ""}}} },
+		"ruletype":  func(c *Config) { c.HeaderRules = []HeaderRule{{Type: "html"}} },
+		"rulename": func(c *Config) {
+			c.HeaderRules = []HeaderRule{{Path: "/videos/*", Values: map[string]string{"Bad Header": "x"}}}
+		},
+	}
+	for name, change := range bad {
+		c := good
+		change(&c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("Expected invalid %s", name)
+		}
+	}
+}
+
+func TestConfigDiff(t *testing.T) {
+	a := Config{Expires: Duration(time.Minute), Headers: map[string]string{"X-Frame-Options": "DENY"}}
+	b := Config{Expires: Duration(time.Hour), Headers: map[string]string{"X-Frame-Options": "DENY"}, CacheSize: 10}
+	changes := a.Diff(&b)
+	if len(changes) != 2 || changes[0].Name != "expires" || changes[1].Name != "cachesize" ||
+		changes[0].Old != Duration(time.Minute) || changes[0].New != Duration(time.Hour) {
+		t.Errorf("Unexpected changes %+v", changes)
+	}
+	if changes = a.Diff(&a); len(changes) != 0 {
+		t.Errorf("Expected no changes but got %+v", changes)
+	}
+}
+
+func TestInvalidConfigKept(t *testing.T) {
+	fsys := fstest.MapFS{
+		"whisper.cfg":           {Data: []byte("languages = [\"en\", \"es\"]")},
+		"template/default.html": {Data: []byte(`{{define "default"}}{{.Content}}{{end}}`)},
+	}
+	fileSys, err := New(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys["whisper.cfg"] = &fstest.MapFile{Data: []byte("languages = [\"en\", \"en\"]")}
+	if _, err = fileSys.Config(); err == nil {
+		t.Errorf("Expected invalid config")
+	}
+	if err = fileSys.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg := fileSys.config(); len(cfg.Languages) != 2 || cfg.Languages[1] != "es" {
+		t.Errorf("Expected the previous config to be kept but got %+v", cfg)
+	}
+}
+
+func TestHeaderRulesName(t *testing.T) {
+	fileSys, err := New(fstest.MapFS{
+		"whisper.cfg": {Data: []byte("[headers]\nX-Frame-Options = \"DENY\"\n\n[[headerrules]]\npath = \"/videos/*\"\n[headerrules.values]\nX-Frame-Options = \"\"\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := fileSys.config()
+	if cfg.Headers["X-Frame-Options"] != "DENY" || len(cfg.HeaderRules) != 1 || cfg.HeaderRules[0].Path != "/videos/*" {
+		t.Errorf("Unexpected headers %+v and rules %+v", cfg.Headers, cfg.HeaderRules)
+	}
+	fileSys, err = New(fstest.MapFS{
+		"whisper.cfg": {Data: []byte("[[headers]]\npath = \"/videos/*\"\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = fileSys.Config(); err == nil || !strings.Contains(err.Error(), "[[headerrules]]") {
+		t.Errorf("Expected an error naming [[headerrules]] but got %v", err)
+	}
+}
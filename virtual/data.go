@@ -0,0 +1,106 @@
+package virtual
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// dataFolder is the hidden folder holding data files for templates.
+const dataFolder = "data"
+
+// loadData reads the TOML, JSON, and CSV files in the data folder, returning
+// them keyed by their path within the folder without the extension, like
+// "menu" for "data/menu.toml" or "team/members" for "data/team/members.csv".
+// Files that cannot be read are logged and skipped.
+func (vfs *FS) loadData() map[string]any {
+	m := make(map[string]any)
+	err := fs.WalkDir(vfs.fs, dataFolder, func(pathname string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || containsSpecialFile(pathname) {
+			return nil
+		}
+		ext := path.Ext(pathname)
+		if ext != ".toml" && ext != ".json" && ext != ".csv" {
+			return nil
+		}
+		b, err := fs.ReadFile(vfs.fs, pathname)
+		if err != nil {
+			slog.Warn("loadData cannot read file", "file", pathname, "error", err)
+			return nil
+		}
+		v, err := parseData(ext, b)
+		if err != nil {
+			slog.Warn("loadData cannot parse file", "file", pathname, "error", err)
+			return nil
+		}
+		m[strings.TrimSuffix(strings.TrimPrefix(pathname, dataFolder+"/"), ext)] = v
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("loadData cannot read data folder", "error", err)
+	}
+	return m
+}
+
+// parseData decodes a data file based on its extension. TOML and JSON files
+// decode to maps and lists, and CSV files decode to a list of maps keyed by
+// the column names in the first row.
+func parseData(ext string, b []byte) (any, error) {
+	var v any
+	switch ext {
+	case ".toml":
+		var t map[string]any
+		err := toml.Unmarshal(b, &t)
+		if err != nil {
+			return nil, fmt.Errorf("parseData: %w", err)
+		}
+		v = t
+	case ".json":
+		err := json.Unmarshal(b, &v)
+		if err != nil {
+			return nil, fmt.Errorf("parseData: %w", err)
+		}
+	case ".csv":
+		records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("parseData: %w", err)
+		}
+		rows := make([]map[string]string, 0, len(records))
+		for i, rec := range records {
+			if i == 0 {
+				continue
+			}
+			row := make(map[string]string, len(rec))
+			for j, col := range records[0] {
+				if j < len(rec) {
+					row[col] = rec[j]
+				}
+			}
+			rows = append(rows, row)
+		}
+		v = rows
+	}
+	return v, nil
+}
+
+// dataFile returns the contents of the named data file and is used in templates.
+func (vfs *FS) dataFile(name string) any {
+	vfs.tplMutex.RLock()
+	defer vfs.tplMutex.RUnlock()
+	v, ok := vfs.dataFiles[strings.Trim(name, "/")]
+	if !ok {
+		slog.Warn("data file not found", "name", name)
+	}
+	return v
+}
@@ -0,0 +1,40 @@
+package virtual
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDataFiles(t *testing.T) {
+	fileSys, err := New(fstest.MapFS{
+		"data/menu.toml":        {Data: []byte("[[links]]\ntitle = \"Home\"\nurl = \"/\"\n")},
+		"data/site.json":        {Data: []byte(`{"name": "Test", "tags": ["a", "b"]}`)},
+		"data/team/members.csv": {Data: []byte("name,role\nAnn,Writer\nBob,Editor\n")},
+		"data/broken.json":      {Data: []byte(`{`)},
+		"data/notes.txt":        {Data: []byte("ignored")},
+		"template/default.html": {Data: []byte(`{{define "default"}}{{range (data "menu").links}}{{.title}}{{end}} {{(data "site").name}} {{range data "team/members"}}{{.name}}:{{.role}} {{end}}{{end}}`)},
+		"index.md":              {Data: []byte("# Home")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(fileSys, "index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != "Home Test Ann:Writer Bob:Editor " {
+		t.Errorf("Unexpected output %q", s)
+	}
+	if fileSys.dataFile("broken") != nil || fileSys.dataFile("notes") != nil {
+		t.Errorf("Expected broken and unknown files to be skipped")
+	}
+	if _, err = fs.Stat(fileSys, "data/site.json"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected data files to be hidden: %v", err)
+	}
+	// only the data folder hides what it holds
+	if _, err = fs.Stat(fileSys, "template/default.html"); err != nil {
+		t.Errorf("Expected only the template folder itself to be hidden: %v", err)
+	}
+}
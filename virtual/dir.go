@@ -13,6 +13,7 @@ import (
 type File struct {
 	FrontMatter FrontMatter
 	Filename    string
+	Path        string // folder holding the file, like "/articles/"
 }
 
 // dir returns a sorted slice of files and is used in templates.
@@ -24,6 +25,7 @@ func (vfs *FS) dir(folderpath string) []File {
 		slog.Error("dir: ReadDir failed", "error", err)
 		return nil
 	}
+	folder := folderURL(folderpath)
 	f := make([]File, 0, len(entries))
 	for _, entry := range entries {
 		if entry.Name() != "index.html" && entry.Name() != "404.html" && entry.Name() != "500.html" {
@@ -35,15 +37,14 @@ func (vfs *FS) dir(folderpath string) []File {
 				fm.Date = fi.ModTime().Local()
 			}
 			if !entry.IsDir() && path.Ext(entry.Name()) == ".html" {
-				err = vfs.readFrontMatter(path.Join(folderpath, strings.TrimSuffix(entry.Name(), ".html")+".md"), &fm)
+				err = vfs.readFrontMatter(vfs.markdownFor(path.Join(folderpath, entry.Name())), &fm)
 				if err != nil {
 					if !errors.Is(err, fs.ErrNotExist) {
 						slog.Warn("readDir problem reading front matter", "error", err)
 					} else if hasMediaFolderPrefix(folderpath) {
-						extensions := []string{".png", ".jpg", ".gif", ".webp", ".jpeg", ".mp4", ".mov", ".webm"}
 						newNm := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
 						// find file with matching extension
-						for _, ext := range extensions {
+						for _, ext := range mediaExtensions {
 							_, err = fs.Stat(vfs, path.Join(folderpath, newNm+ext))
 							if err == nil {
 								fm.OriginalFile = newNm + ext
@@ -53,7 +54,12 @@ func (vfs *FS) dir(folderpath string) []File {
 					}
 				}
 			}
-			f = append(f, File{FrontMatter: fm, Filename: entry.Name()})
+			if entry.IsDir() && vfs.isBundle(path.Join(folderpath, entry.Name())) {
+				// page bundles are listed like pages
+				f = append(f, vfs.bundleFile(path.Join(folderpath, entry.Name()), fm))
+				continue
+			}
+			f = append(f, File{FrontMatter: fm, Filename: entry.Name(), Path: folder})
 		}
 	}
 	return f
@@ -65,6 +71,18 @@ func sortByTime(f []File) []File {
 	return f
 }
 
+// sortByWeight sorts the files by weight in reverse order, using time
+// to break ties.
+func sortByWeight(f []File) []File {
+	sort.Slice(f, func(i, j int) bool {
+		if f[i].FrontMatter.Weight != f[j].FrontMatter.Weight {
+			return f[j].FrontMatter.Weight < f[i].FrontMatter.Weight
+		}
+		return f[j].FrontMatter.Date.Before(f[i].FrontMatter.Date)
+	})
+	return f
+}
+
 // sortByName sorts the files by the time in reverse order
 func sortByName(f []File) []File {
 	sort.Slice(f, func(i, j int) bool { return f[j].Filename < f[i].Filename })
@@ -106,10 +124,11 @@ func match(s string, pat ...string) bool {
 	return false
 }
 
-// next returns the previous file in the list.
+// next returns the next file in the list. The current file is
+// matched by Filename or by its full path.
 func next(f []File, current string) *File {
 	for i := range f {
-		if f[i].Filename == current {
+		if f[i].is(current) {
 			if i > 0 {
 				return &f[i-1]
 			}
@@ -119,10 +138,11 @@ func next(f []File, current string) *File {
 	return nil
 }
 
-// prev returns the previous file in the list.
+// prev returns the previous file in the list. The current file is
+// matched by Filename or by its full path.
 func prev(f []File, current string) *File {
 	for i := range f {
-		if f[i].Filename == current {
+		if f[i].is(current) {
 			if i < len(f)-1 {
 				return &f[i+1]
 			}
@@ -131,3 +151,17 @@ func prev(f []File, current string) *File {
 	}
 	return nil
 }
+
+// is checks if the file has the given name or full path.
+func (f File) is(name string) bool {
+	return f.Filename == name || (f.Path != "" && path.Join(f.Path, f.Filename) == name)
+}
+
+// folderURL converts a folder in the file system to a URL path ending in a slash.
+func folderURL(folder string) string {
+	folder = path.Join("/", folder)
+	if folder != "/" {
+		folder += "/"
+	}
+	return folder
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"sync"
 	"time"
 )
 
@@ -139,3 +140,19 @@ func (fi fileInfo) IsDir() bool {
 func (fi fileInfo) Sys() interface{} {
 	return nil
 }
+
+// lazyFileInfo is a fileInfo for a rendered file whose size is computed the first
+// time it is asked for, because finding the size means rendering the file.
+type lazyFileInfo struct {
+	fileInfo
+	once sync.Once
+	size func() int64
+}
+
+// Size returns the length in bytes of the rendered file.
+func (fi *lazyFileInfo) Size() int64 {
+	fi.once.Do(func() {
+		fi.sz = fi.size()
+	})
+	return fi.sz
+}
@@ -1,8 +1,10 @@
 package virtual
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
+	"path"
 	"regexp"
 	"strings"
 	"time"
@@ -13,11 +15,16 @@ import (
 // FrontMatter holds data scraped from a Markdown page.
 type FrontMatter struct {
 	Title        string    `toml:"title"`        // Title of this page
+	Description  string    `toml:"description"`  // Short description of this page
 	Date         time.Time `toml:"date"`         // Date the article appears
 	Template     string    `toml:"template"`     // The name of the template to use
 	Tags         []string  `toml:"tags"`         // Tags to assign to this article
 	Redirect     string    `toml:"redirect"`     // Issue a redirect to another location
 	OriginalFile string    `toml:"originalfile"` // The original file (markdown or image)
+	Series       string    `toml:"series"`       // Name of a series of articles this page belongs to
+	Weight       int       `toml:"weight"`       // Ordering of this page in a series or menu
+	Templated    bool      `toml:"templated"`    // Run the Markdown through the template engine first
+	Bundle       bool      `toml:"bundle"`       // The folder of this index page is a page bundle
 }
 
 // fmRegexp is the regular expression used to split out front matter.
@@ -41,6 +48,10 @@ func (vfs *FS) readFrontMatter(name string, fm *FrontMatter) error {
 	if err != nil {
 		return fmt.Errorf("readFrontMatter: %w", err)
 	}
+	err = vfs.readDefaults(path.Dir(name), fm)
+	if err != nil {
+		return fmt.Errorf("readFrontMatter: %w", err)
+	}
 	fmb, _ := extractFrontMatter(b)
 	err = toml.Unmarshal(fmb, fm)
 	if err != nil {
@@ -48,3 +59,33 @@ func (vfs *FS) readFrontMatter(name string, fm *FrontMatter) error {
 	}
 	return nil
 }
+
+// defaultsFile is the name of the file holding front matter defaults for a folder.
+const defaultsFile = ".defaults.toml"
+
+// readDefaults applies the front matter defaults for the given folder to fm.
+// Defaults in each folder from the root down to the given folder are applied in
+// turn, so that deeper folders override values from their parents.
+func (vfs *FS) readDefaults(folder string, fm *FrontMatter) error {
+	folder = strings.Trim(path.Clean("/"+folder), "/")
+	folders := []string{"."}
+	if folder != "" {
+		parts := strings.Split(folder, "/")
+		for i := range parts {
+			folders = append(folders, path.Join(parts[:i+1]...))
+		}
+	}
+	for _, f := range folders {
+		b, err := fs.ReadFile(vfs.fs, path.Join(f, defaultsFile))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("readDefaults: %w", err)
+		}
+		err = toml.Unmarshal(b, fm)
+		if err != nil {
+			return fmt.Errorf("readDefaults: %s: %w", path.Join(f, defaultsFile), err)
+		}
+	}
+	return nil
+}
@@ -4,12 +4,16 @@ and other files in a web format. It includes a template system and helpers for p
 a nice static web view and an easy-to-maintain format.
 
 A special file "whisper.cfg" at the root exposes settings you can use via the Config() function.
-This file is hidden from view.
+This file is hidden from view, as is a Netlify-style "_redirects" file whose rules are added to the config.
+Settings that are not valid are rejected, and templates keep using the config loaded before.
 
 A special folder "template" at the root holds HTML templates should you want to customize. At
 minimum, a template called "default" is required for handling Markdown files, a template
-called "image" is required for handling image files, and a template called "video" is required
-for handling video files.
+called "image" is required for handling image files, a template called "video" is required
+for handling video files, and a template called "audio" is required for handling audio files.
+
+A special folder "data" at the root holds TOML, JSON, and CSV files that are loaded along with the
+templates and read using the "data" template function. This folder is hidden from view.
 
 Hidden files and folders (those starting with ".") are ignored.
 
@@ -28,15 +32,62 @@ file is found, a virtual file "/foo/bar.html" is created that will render an HTM
 The underlying image file is not hidden, because it needs to be served for the HTML. Note that this
 special image handling only happens when the top-level folder is one of the following:
 
-	"photos", "images", "pictures", "cartoons", "toons", "sketches", "artwork", "drawings", "videos", "movies"
-
-Similarly, a video file (MP4, MOV, WEBM) will be handled by rendering an HTML file using the "video" template.
+	"photos", "images", "pictures", "cartoons", "toons", "sketches", "artwork", "drawings", "videos", "movies",
+	"audio", "music", "podcasts"
+
+Similarly, a video file (MP4, MOV, WEBM) will be handled by rendering an HTML file using the "video" template,
+and an audio file (MP3, OGG, M4A, FLAC) will be handled by rendering an HTML file using the "audio" template.
+Audio templates also receive the duration and tags (ID3, Vorbis comments, or iTunes metadata) of the file
+as virtual.MediaInfo. Video templates receive the duration and frame size of MP4, MOV, and WEBM files.
+If an image has the same base name as a video or audio file (like "clip.jpg" and "clip.mp4"), it is
+named in MediaInfo.Poster for use as a poster frame or cover art, rather than getting its own page.
+
+Rendered files report the latest modification time of what they depend on: their source file, the
+templates, "whisper.cfg", and data files, the front matter defaults that apply, and the folder of the
+source and its entries, which a page can list with dir. The site map reports the latest time of the whole
+site. Unlike the time of rendering, it stays the same until something the page depends on changes, so that
+it can be used for Last-Modified. Pages that show other folders through functions like tree and related
+keep their time when only those folders change. The pages of the whole site, used by series and related,
+are found once and kept until a check, made at most every couple of seconds, finds that the content or
+templates have changed.
+Folder listings report the size and time of the rendered file rather than its source, and render the file
+to find the size only when it is asked for. A cache in front of the file system should not ask for the
+sizes of every entry when it caches a folder, like cachefs does unless NoStat is set.
+
+A compressed variant of a text file or rendered page, like "index.html.br", "index.html.zst", or "index.html.gz",
+is made with Brotli, Zstandard, or gzip when it is opened, unless a precompressed file of that name exists.
+Moderate compression levels are used, because the variants are made again after each cache miss.
+Compressed variants do not appear in folder listings.
+
+# Podcast Feeds
+
+A media folder containing audio files also presents a virtual "feed.xml" file, which is an iTunes-compatible
+podcast RSS feed listing the audio files with their enclosure lengths, MIME types, and durations. The channel
+title and description are taken from the folder's "index.md" front matter. Set "baseurl" in "whisper.cfg" so
+that the feed contains absolute URLs; a warning is logged without it. File names are escaped in the URLs.
 
 # Site Map
 
-If a file in the root names "sitemap.txt" is present, it will be run as template that can list the files
-of the site map. This allows you to customize what your site map looks like. The site map receives only
-the list of file names as a slice of strings.
+If a file in the root named "sitemap.txt" or "sitemap.xml" is present, it will be run as template that can
+list the files of the site map. This allows you to customize what your site map looks like. The site map
+receives only the list of file names as a slice of strings, and can use the "join", "translations",
+"absurl", and "site" functions. The "translations" function is useful for hreflang entries, and "site"
+returns the site-wide variables that other templates receive as .Site.
+
+# Site Variables
+
+The [site] table in "whisper.cfg" holds site-wide variables with any keys, like title or author. They are
+available to every template as .Site, including image, video, and audio templates and shortcodes.
+
+# Languages
+
+Pages can be published in several languages by listing them in "whisper.cfg", starting with the default:
+
+	languages = ["en", "es"]
+
+A Markdown file named for a language, like "about.es.md", is a translation of "about.md". It is hidden
+from folder listings, and is instead served from a folder named for the language, like "/es/about.html".
+Templates receive the language of the page and a list of its translations (virtual.Translation).
 
 # Front Matter
 
@@ -55,19 +106,35 @@ Front matter may include:
 	Name       Type                  Description
 	---------  -----------------     -----------------------------------------
 	title         string             Title of page
+	description   string             Short description of page
 	date          time               Publish date
-	tags          array of strings   Tags for the articles (not used yet)
+	tags          array of strings   Tags for the articles, used to find related pages
 	template      string             Override the template to render this file
 	redirect      string             You can use this to issue an HTML meta-tag redirect
 	originalfile  string             The original filename (markdown or image)
+	series        string             Name of a series of articles the page belongs to
+	weight        int                Ordering of the page in a series
+	templated     bool               Run the Markdown through the template engine before rendering it
+	bundle        bool               In an index.md, makes the folder a page bundle
+
+A hidden ".defaults.toml" file holds front matter defaults for the Markdown files in its folder and subfolders.
+The defaults of each folder from the root down are applied in turn, followed by the page's own front matter,
+so that the most specific value wins. Arrays like tags are replaced rather than combined.
 
 # Templates
 
-The system uses standard Go templates from the `html/template` package, and includes three default templates,
-"default", "image", and "video". Templates are stored in the "template" top-level folder with the extension ".html".
+The system uses standard Go templates from the `html/template` package, and includes four default templates,
+"default", "image", "video", and "audio". Templates are stored in the "template" top-level folder with the extension ".html".
 
-Templates are passed page information (virtual.PageInfo), front matter (virtual.FrontMatter), and rendered HTML from
-Markdown (template.HTML), and can use these data elements in their processing. Template also make
+Templates in subfolders of the "template" folder apply to pages in the matching folder of the site. Each such file
+is a template named by its path without the extension, like "articles/default" for "template/articles/default.html".
+A page in "articles/2024" that uses the "default" template is rendered with "articles/2024/default" if present, then
+"articles/default", and finally "default". The same lookup is used for "image", "video", "audio", templates named
+in front matter, and shortcodes. Templates defined inside these files must not reuse the name of another template,
+like "default", which would change it for the whole site; loading the templates fails instead.
+
+Templates are passed page information (virtual.PageInfo), front matter (virtual.FrontMatter), rendered HTML from
+Markdown (template.HTML), media information (virtual.MediaInfo), and the language and translations of the page, and can use these data elements in their processing. Template also make
 the following helper functions available:
 
 	dir(path string) []virtual.File
@@ -76,6 +143,8 @@ the following helper functions available:
 		Sort by name (reverse)
 	sortbytime([]virtual.File) []virtual.File
 		Sort by time (reverse)
+	sortbyweight([]virtual.File) []virtual.File
+		Sort by weight, then time (reverse)
 	match(string, ...string) bool
 		Match string against file patterns
 	filter([]virtual.File, ...string) []virtual.File
@@ -85,9 +154,19 @@ the following helper functions available:
 	ext(path string) string
 		The same as path.Ext
 	prev([]virtual.File, string) *virtual.File
-		Find the previous file based on Filename
+		Find the previous file based on Filename or full path
 	next([]virtual.File, string) *virtual.File
-		Find the next file based on Filename
+		Find the next file based on Filename or full path
+	paginate(virtual.PageInfo, []virtual.File, int) *virtual.Pager
+		Split the list into pages of the given size and return the current page
+	series(name string) []virtual.File
+		Return the pages of the named series across the site, sorted by weight (reverse)
+	tree(path string) *virtual.Node
+		Return the folder hierarchy starting at the given folder
+	related(path string, limit int) []virtual.File
+		Return up to limit pages sharing tags with the given page, most shared tags first
+	translations(path string) []virtual.Translation
+		Return the versions of the given page in each language
 	reverse([]virtual.File) []virtual.File
 		Reverse the list
 	trimsuffix(string, string) string
@@ -102,6 +181,93 @@ the following helper functions available:
 		Read front matter from file
 	now() time.Time
 		Current time
+	date(layout string, time.Time, zone ...string) string
+		Format the time with a layout like "Jan 2, 2006" or a name like "rfc3339", in an optional time zone
+	wordcount(text) int
+		Number of words in text or HTML
+	readingtime(text) int
+		Estimated minutes to read text or HTML
+	truncatehtml(int, html) template.HTML
+		Shorten HTML to about n characters of text, closing open tags
+	summary(html) template.HTML
+		HTML before a "<!--more-->" comment, or else the first paragraph
+	slugify(string) string
+		Convert text to a URL-friendly form like "hello-world"
+	where([]File, field string, value) []File
+		Files whose front matter field (or any element of a list like tags) matches the value
+	sortby([]File, field string, order ...string) []File
+		Sort a copy by a front matter field, ascending unless the order is "desc"
+	first(list) any
+		First item of a list
+	last(list) any
+		Last item of a list
+	limit(int, list) list
+		At most the first n items of a list
+	add, sub, mul, div, mod(int, int) int
+		Integer math
+	dict(key, value, ...) map[string]any
+		Make a map, such as to pass several values to a template
+	slice(items ...) []any
+		Make a list; this replaces the built-in slice function
+	absurl(path string) string
+		Absolute URL of the path using the baseurl setting
+	resources(path string) []virtual.File
+		Return the files of the page bundle in the given folder, other than Markdown files
+	asset(path string) virtual.Asset
+		Return the fingerprinted URL and subresource integrity hash of a static file
+	data(name string) any
+		Contents of a data file, named by its path in the data folder without the extension. TOML and JSON
+		files are maps and lists, and CSV files are a list of maps keyed by the column names in the first row
+
+# Templated Markdown
+
+When the front matter sets "templated = true", the Markdown is executed as an html/template before it is
+rendered, with the same data and functions as the page template. For example, an "index.md" can list its
+own folder with {{range dir .Page.Path}}. Shortcodes are expanded before the template runs. The
+"frontmatter" function does not render the page, so a page may read its own front matter. Pages included
+with the "markdown" function are nested at most four deep, so a page that includes itself stops there.
+
+# Shortcodes
+
+Markdown can call templates named "shortcodes/<name>" using shortcodes like {{< figure src="dog.png" >}}.
+Parameters are given as name="value" or as positional values, and a shortcode may enclose Markdown
+when closed with {{< /name >}}. Shortcodes may be nested. The template receives a virtual.Shortcode
+holding the Name, Params, Args, the rendered Inner content, and the page's FrontMatter and Page.
+A shortcode written as a comment, starting with "{{</*", is shown without running it, also on templated
+pages. Shortcode output comes from html/template, so parameters are escaped like any other template data.
+
+# Page Bundles
+
+A folder whose index.md sets "bundle = true" is a page bundle, where the page and the files next to it form
+one page. Media files in a bundle are not given pages of their own, even inside media folders, and the "dir"
+template function lists the bundle like a page with a Filename like "trip/". The "resources" template
+function lists the files of a bundle.
+
+# Assets
+
+The "asset" template function returns a virtual.Asset for a static file, holding a URL with a hash of the
+content in the name, like "/static/site.0123456789abcdef.css", and a sha384 subresource integrity hash.
+The file system serves fingerprinted names from the original file for as long as the content matches.
+CurrentAsset returns the current URL for an older fingerprinted name.
+CSS and JavaScript files are minified conservatively, by removing comments and extra whitespace.
+
+# Navigation
+
+The "tree" template function returns the folders of the site as a virtual.Node, which embeds the virtual.File
+for the folder's index page and holds its subfolders in Children, sorted by weight in reverse like "sortbyweight"
+and then title. Titles and weights come from each folder's index.md front matter. Hidden folders, folders
+starting with ".", and folders without any pages are left out. Trees are kept until the site's content or
+templates change. PageInfo.Breadcrumbs lists the index pages of the folders leading
+to the current page, starting at the root.
+
+# Pagination
+
+Long listings can be split into pages using the "paginate" template function. The first page is the
+listing itself, like "/articles/" or "/photos/list.html", and later pages are virtual files
+like "/articles/page/2.html" or "/photos/list/page/2.html" that render the same Markdown file with
+PageInfo.Number set to the page number. The virtual.Pager returned by "paginate" holds the files on
+the current page and the URLs of the first, last, previous, and next pages. Pages past the last one,
+and later pages of Markdown files that don't paginate, do not exist.
 
 # Index Files
 
@@ -134,10 +300,17 @@ import (
 // FS provides a virtual view of the file system suitable for serving Markdown
 // and other files in a web format.
 type FS struct {
-	fs       fs.FS
-	tpl      *template.Template
-	tplMutex sync.RWMutex
-	done     chan bool //used to stop the template reloader
+	fs         fs.FS
+	tpl        *template.Template
+	nested     []*template.Template // clones of tpl for rendering included pages
+	cfg        *Config              // settings loaded along with the templates
+	funcs      template.FuncMap
+	dataFiles  map[string]any // contents of the data folder, loaded along with the templates
+	tplModTime time.Time      // latest modification time of the templates, config, and data files
+	observer   Observer       // notified of rendering and template reloading
+	index      siteIndex      // what is known about the content of the site
+	tplMutex   sync.RWMutex
+	done       chan bool //used to stop the template reloader
 }
 
 // New returns a new FS that presents a virtual view of innerFS.
@@ -170,6 +343,14 @@ func (vfs *FS) Close() error {
 	return nil
 }
 
+// Reload loads the templates, configuration, and data files again. If the templates
+// cannot be parsed, the previous ones are kept and the error is returned.
+func (vfs *FS) Reload() error {
+	_, err := vfs.loadTemplates()
+	vfs.templatesLoaded(err)
+	return err
+}
+
 // reloadTemplates is started as a goroutine to periodically reload the templates
 // in case of edits.
 func (vfs *FS) reloadTemplates(tplReload time.Duration) {
@@ -180,7 +361,7 @@ func (vfs *FS) reloadTemplates(tplReload time.Duration) {
 		case <-vfs.done:
 			return
 		case <-t.C:
-			_, err := vfs.loadTemplates()
+			err := vfs.Reload()
 			if err != nil {
 				slog.Error("Failed to load templates", "error", err)
 			} else {
@@ -215,10 +396,10 @@ func (vfs *FS) Open(name string) (fs.File, error) {
 	if err != nil {
 		// for files that don't exist, check for underlying matching files
 		if errors.Is(err, fs.ErrNotExist) && path.Ext(name) == ".html" {
-			extensions := []string{".md", ".png", ".jpg", ".gif", ".webp", ".jpeg", ".mp4", ".mov", ".webm"}
-			// if it's not in an media folder, only check markdown files
-			if !hasMediaFolderPrefix(name) {
-				extensions = extensions[:1]
+			extensions := []string{".md"}
+			// if it's in a media folder, also check media files, unless they belong to a page bundle
+			if hasMediaFolderPrefix(name) && !vfs.isBundle(path.Dir(name)) {
+				extensions = append(extensions, mediaExtensions...)
 			}
 			newNm := strings.TrimSuffix(name, path.Ext(name))
 			// find file with matching extension
@@ -232,12 +413,53 @@ func (vfs *FS) Open(name string) (fs.File, error) {
 						return vfs.newMarkdownFile(f, newNm+".html")
 					case ".mp4", ".mov", ".webm":
 						return vfs.newVideoFile(f, newNm+".html")
+					case ".mp3", ".ogg", ".m4a", ".flac":
+						return vfs.newAudioFile(f, newNm+".html")
 					default:
 						return vfs.newImageFile(f, newNm+".html")
 					}
 				}
 			}
 		}
+		// pages in other languages are rendered from Markdown variants like "about.es.md"
+		if errors.Is(err, fs.ErrNotExist) {
+			if lang, rest, ok := vfs.splitLanguage(name); ok {
+				return vfs.openLanguage(name, lang, rest)
+			}
+		}
+		// pages of a paginated listing are rendered from the listing's Markdown
+		if errors.Is(err, fs.ErrNotExist) {
+			if base, number, ok := splitPageName(name); ok {
+				for _, md := range []string{path.Join(base, "index.md"), base + ".md"} {
+					f, err2 := vfs.fs.Open(md)
+					if err2 == nil {
+						defer f.Close()
+						page, err := vfs.newMarkdownPage(f, strings.TrimSuffix(md, ".md")+".html", number)
+						if errors.Is(err, fs.ErrNotExist) {
+							// the listing has fewer pages
+							return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+						}
+						return page, err
+					}
+				}
+			}
+		}
+		// audio folders get a generated podcast feed
+		if errors.Is(err, fs.ErrNotExist) && path.Base(name) == "feed.xml" && hasMediaFolderPrefix(name) && !vfs.isBundle(path.Dir(name)) {
+			return vfs.newPodcastFile(path.Dir(name), name)
+		}
+		// fingerprinted assets are served from the original file
+		if errors.Is(err, fs.ErrNotExist) && fingerprintRegexp.MatchString(name) {
+			if af, err2 := vfs.newAssetFile(name); err2 == nil {
+				return af, nil
+			}
+		}
+		// compressed variants of text files and rendered pages are made from the original
+		if errors.Is(err, fs.ErrNotExist) && encoders[path.Ext(name)] != nil {
+			if cf, err2 := vfs.newCompressedFile(name); err2 == nil {
+				return cf, nil
+			}
+		}
 		// no matching underlying file; return error from opening the underlying file
 		return f, err
 	}
@@ -257,7 +479,7 @@ func (vfs *FS) Open(name string) (fs.File, error) {
 
 	// The sitemap file, if present, needs to be handled as a virtual
 	// file to process the template.
-	if name == "sitemap.txt" {
+	if isSitemap(name) {
 		defer f.Close()
 		return vfs.newSitemapFile(f, name)
 	}
@@ -265,3 +487,26 @@ func (vfs *FS) Open(name string) (fs.File, error) {
 	// Return the unprocessed, regular file
 	return f, nil
 }
+
+// Stat returns a FileInfo describing the named file.
+//
+// Regular files that are served as-is are checked using the underlying
+// file system, so that large media files need not be opened.
+func (vfs *FS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+	if isHiddenFile(name) || (name != "." && containsSpecialFile(name)) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	fi, err := fs.Stat(vfs.fs, name)
+	if err == nil && fi.Mode().IsRegular() && !isSitemap(name) {
+		return fi, nil
+	}
+	f, err := vfs.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
@@ -2,13 +2,16 @@ package virtual
 
 import (
 	"errors"
+	"html/template"
 	"io"
 	"io/fs"
 	"net/http"
 	"os"
+	"path"
 	"strings"
 	"sync"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -277,12 +280,9 @@ func TestFileSize(t *testing.T) {
 
 	t.Logf("Using ReadDir size is %d, using Stat size is %d", fi1.Size(), fi2.Size())
 
-	// TODO: Reenable test
-	/*
-		if fi1.Size() != fi2.Size() {
-			t.Errorf("Sizes don't match: %d vs %d", fi1.Size(), fi2.Size())
-		}
-	*/
+	if fi1.Size() != fi2.Size() {
+		t.Errorf("Sizes don't match: %d vs %d", fi1.Size(), fi2.Size())
+	}
 }
 
 func TestReadDirLoop(t *testing.T) {
@@ -334,3 +334,442 @@ func TestReadDirLoop(t *testing.T) {
 		t.Log(dirs)
 	}
 }
+
+func TestPagination(t *testing.T) {
+	files := make([]File, 12)
+	tests := []struct {
+		page  PageInfo
+		count int
+		prev  string
+		next  string
+		last  string
+	}{
+		{PageInfo{Path: "/articles/", Filename: "index.html"}, 5, "", "/articles/page/2.html", "/articles/page/3.html"},
+		{PageInfo{Path: "/articles/", Filename: "index.html", Number: 2}, 5, "/articles/", "/articles/page/3.html", "/articles/page/3.html"},
+		{PageInfo{Path: "/photos/", Filename: "list.html", Number: 3}, 2, "/photos/list/page/2.html", "", "/photos/list/page/3.html"},
+	}
+	for _, test := range tests {
+		p := paginate(test.page, files, 5)
+		if len(p.Items) != test.count || p.Prev != test.prev || p.Next != test.next || p.Last != test.last || p.TotalPages != 3 {
+			t.Errorf("Unexpected pager for %#v: %#v", test.page, p)
+		}
+	}
+
+	fileSys, err := New(os.DirFS("../example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi, err := fs.Stat(fileSys, "articles/page/2.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Name() != "2.html" || fi.Size() == 0 {
+		t.Errorf("Unexpected page: %s %d", fi.Name(), fi.Size())
+	}
+	for _, name := range []string{"articles/page/1.html", "articles/page/02.html", "nothere/page/2.html", "articles/page/999.html", "about/page/2.html"} {
+		_, err = fs.Stat(fileSys, name)
+		var pe *fs.PathError
+		if !errors.Is(err, fs.ErrNotExist) || !errors.As(err, &pe) || pe.Path != name {
+			t.Errorf("Expected %q to not exist: %v", name, err)
+		}
+	}
+}
+
+func TestSeriesAndRelated(t *testing.T) {
+	fileSys, err := New(os.DirFS("../example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := fileSys.series("Meet the Dude")
+	if len(s) != 2 || s[0].Filename != "logo.html" || s[1].Filename != "green.html" {
+		t.Errorf("Unexpected series: %#v", s)
+	}
+	if f := prev(s, "/articles/logo.html"); f == nil || f.Filename != "green.html" {
+		t.Errorf("Expected green.html before logo.html: %#v", f)
+	}
+	r := fileSys.related("/articles/logo.html", 5)
+	if len(r) != 2 {
+		t.Errorf("Expected 2 related pages but got %#v", r)
+	}
+	for _, f := range r {
+		if f.Path != "/articles/" || f.Filename == "logo.html" {
+			t.Errorf("Unexpected related page %q", path.Join(f.Path, f.Filename))
+		}
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	fileSys, err := New(os.DirFS("../example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := fs.ReadDir(fileSys, "es")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "about.html" {
+		t.Errorf("Expected only about.html in es but got %v", entries)
+	}
+	root, err := fs.ReadDir(fileSys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range root {
+		if strings.HasPrefix(entry.Name(), "about.es") {
+			t.Errorf("Variant %q should not be listed", entry.Name())
+		}
+	}
+	b, err := fs.ReadFile(fileSys, "es/about.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `lang="es"`) || !strings.Contains(string(b), "Acerca de este sitio") {
+		t.Errorf("Expected Spanish page but got %s", b)
+	}
+	tr := fileSys.translations("/es/about.html")
+	if len(tr) != 2 || tr[0] != (Translation{"en", "/about.html"}) || tr[1] != (Translation{"es", "/es/about.html"}) {
+		t.Errorf("Unexpected translations: %#v", tr)
+	}
+	if _, err = fs.Stat(fileSys, "es/how.html"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected untranslated page to not exist: %v", err)
+	}
+}
+
+func TestTemplatedMarkdown(t *testing.T) {
+	fileSys, err := New(os.DirFS("../example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(fileSys, "podcasts/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `<li><a href="tone.html">tone</a>`) {
+		t.Errorf("Expected the folder listing in the page but got %s", b)
+	}
+}
+
+func TestSelfReferencingPage(t *testing.T) {
+	fileSys, err := New(fstest.MapFS{
+		"template/default.html":          {Data: []byte(`{{define "default"}}{{.Content}}{{end}}`)},
+		"template/shortcodes/again.html": {Data: []byte(`[{{markdown "/b.html"}}]`)},
+		"index.md":                       {Data: []byte("+++\ntitle = \"Home\"\ntemplated = true\n+++\n{{ (frontmatter \"/index.html\").Title }} {{markdown \"/index.html\"}}\n")},
+		"b.md":                           {Data: []byte("B {{< again >}}\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, expect := range map[string]string{"index.html": "Home", "b.html": "B ["} {
+		b, err := fs.ReadFile(fileSys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(b), expect); n != maxDepth+1 {
+			t.Errorf("Expected %s nested %d times but got %d in %s", name, maxDepth+1, n, b)
+		}
+	}
+}
+
+func TestTemplateFolderDefines(t *testing.T) {
+	fsys := fstest.MapFS{
+		"template/default.html":          {Data: []byte(`{{define "default"}}root {{template "articles/byline"}}{{end}}`)},
+		"template/articles/default.html": {Data: []byte(`articles {{template "articles/byline"}}{{define "articles/byline"}}by me{{end}}`)},
+		"articles/how.md":                {Data: []byte("# How")},
+	}
+	fileSys, err := New(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(fileSys, "articles/how.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "articles by me" {
+		t.Errorf("Unexpected page %q", b)
+	}
+
+	// a folder can't replace a template of the whole site
+	fsys["template/articles/x.html"] = &fstest.MapFile{Data: []byte(`{{define "default"}}articles{{end}}`)}
+	if _, err = New(fsys); err == nil || !strings.Contains(err.Error(), `"default"`) {
+		t.Errorf("Expected an error for redefining default but got %v", err)
+	}
+}
+
+func TestLookupTemplate(t *testing.T) {
+	tpl := template.Must(template.New("default").Parse(`x`))
+	template.Must(tpl.New("articles/default").Parse(`y`))
+	template.Must(tpl.New("articles/2024/listing").Parse(`z`))
+	tests := []struct{ folder, name, expect string }{
+		{"/", "default", "default"},
+		{"/articles/", "default", "articles/default"},
+		{"articles/2024/", "default", "articles/default"},
+		{"/articles/2024/", "listing", "articles/2024/listing"},
+		{"/articles/", "listing", "listing"},
+		{"/photos/", "default", "default"},
+	}
+	for _, test := range tests {
+		if n := lookupTemplate(tpl, test.folder, test.name); n != test.expect {
+			t.Errorf("Expected %q for %q in %q but got %q", test.expect, test.name, test.folder, n)
+		}
+	}
+
+	fileSys, err := New(os.DirFS("../example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(fileSys, "photos/lanterns600.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `src="/photos/lanterns600.png"`) {
+		t.Errorf("Expected the image template from template/photos/default.html but got %s", b)
+	}
+}
+
+func TestFrontMatterDefaults(t *testing.T) {
+	fileSys, err := New(fstest.MapFS{
+		".defaults.toml":            {Data: []byte(`template = "listing"` + "\n" + `tags = ["site"]`)},
+		"a/.defaults.toml":          {Data: []byte(`tags = ["a"]` + "\n" + `description = "in a"`)},
+		"a/b/page.md":               {Data: []byte("# Page")},
+		"a/b/tagged.md":             {Data: []byte("+++\ntags = [\"mine\"]\n+++\n# Tagged")},
+		"c.md":                      {Data: []byte("# C")},
+		"template/default.html":     {Data: []byte(`{{define "default"}}default{{end}}`)},
+		"template/listing.html":     {Data: []byte(`{{define "listing"}}{{.FrontMatter.Tags}} {{.FrontMatter.Description}}{{end}}`)},
+		"template/a/b/listing.html": {Data: []byte(`b {{.FrontMatter.Tags}}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"c.html":          "[site] ",
+		"a/b/page.html":   "b [a]",
+		"a/b/tagged.html": "b [mine]",
+	}
+	for name, expect := range tests {
+		b, err := fs.ReadFile(fileSys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != expect {
+			t.Errorf("Expected %q for %s but got %q", expect, name, b)
+		}
+	}
+	files := fileSys.dir("/a/b/")
+	if len(files) != 2 || files[0].FrontMatter.Description != "in a" {
+		t.Errorf("Expected defaults in folder listing but got %#v", files)
+	}
+	if fm := fileSys.fm("/a/b/page.html"); fm == nil || fm.Template != "listing" {
+		t.Errorf("Expected defaults from frontmatter but got %#v", fm)
+	}
+	if _, err := fs.Stat(fileSys, "a/.defaults.toml"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected defaults file to be hidden: %v", err)
+	}
+}
+
+func TestTreeAndBreadcrumbs(t *testing.T) {
+	fileSys, err := New(os.DirFS("../example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := fileSys.tree("/")
+	if root == nil {
+		t.Fatal("Expected a tree")
+	}
+	var titles []string
+	for _, child := range root.Children {
+		titles = append(titles, child.FrontMatter.Title)
+	}
+	if strings.Join(titles, ",") != "Articles,Photos,Podcasts" {
+		t.Errorf("Expected folders sorted by weight but got %v", titles)
+	}
+	if root.Children[0].Path != "/articles/" || root.Children[0].Filename != "index.html" {
+		t.Errorf("Unexpected node %#v", root.Children[0].File)
+	}
+	if fileSys.tree("/") != root {
+		t.Error("Expected the tree to be kept")
+	}
+
+	crumbs := fileSys.breadcrumbs("articles/how.html")
+	if len(crumbs) != 2 || crumbs[0].Path != "/" || crumbs[1].FrontMatter.Title != "Articles" {
+		t.Errorf("Unexpected breadcrumbs %#v", crumbs)
+	}
+	if crumbs = fileSys.breadcrumbs("articles/index.html"); len(crumbs) != 1 || crumbs[0].Path != "/" {
+		t.Errorf("Expected only the root for an index page but got %#v", crumbs)
+	}
+	if crumbs = fileSys.breadcrumbs("index.html"); len(crumbs) != 0 {
+		t.Errorf("Expected no breadcrumbs for the home page but got %#v", crumbs)
+	}
+}
+
+func TestSiteVariables(t *testing.T) {
+	fileSys, err := New(fstest.MapFS{
+		"whisper.cfg":           {Data: []byte("baseurl = \"https://example.com\"\n[site]\ntitle = \"My Site\"\nyear = 2024\n")},
+		"template/default.html": {Data: []byte(`{{define "default"}}{{.Site.title}} {{.Site.year}}{{end}}{{define "image"}}{{.Site.title}} image{{end}}`)},
+		"index.md":              {Data: []byte("# Home")},
+		"photos/cat.png":        {Data: []byte("png")},
+		"sitemap.txt":           {Data: []byte(`{{define "sitemap"}}{{site.title}}{{range .}} {{absurl .}}{{end}}{{end}}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"index.html":      "My Site 2024",
+		"photos/cat.html": "My Site image",
+		"sitemap.txt":     "My Site https://example.com/ https://example.com/photos/ https://example.com/photos/cat.html https://example.com/photos/cat.png https://example.com/sitemap.txt",
+	}
+	for name, expect := range tests {
+		b, err := fs.ReadFile(fileSys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != expect {
+			t.Errorf("Expected %q for %s but got %q", expect, name, b)
+		}
+	}
+}
+
+func TestPageBundles(t *testing.T) {
+	fileSys, err := New(fstest.MapFS{
+		"photos/cat.png":        {Data: []byte("png")},
+		"photos/trip/index.md":  {Data: []byte("+++\ntitle = \"Trip\"\nbundle = true\n+++\n# Trip")},
+		"photos/trip/beach.jpg": {Data: []byte("jpg")},
+		"photos/trip/notes.md":  {Data: []byte("# Notes")},
+		"photos/trip/.hidden":   {Data: []byte("x")},
+		"template/default.html": {Data: []byte(`{{define "default"}}{{range resources .Page.Path}}{{.Filename}} {{end}}{{end}}{{define "image"}}image{{end}}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(fileSys, "photos/trip/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "beach.jpg " {
+		t.Errorf("Unexpected resources %q", b)
+	}
+	if _, err = fs.Stat(fileSys, "photos/trip/beach.html"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected no page for media in a bundle: %v", err)
+	}
+	if _, err = fs.Stat(fileSys, "photos/cat.html"); err != nil {
+		t.Errorf("Expected a page for media outside a bundle: %v", err)
+	}
+	entries, err := fs.ReadDir(fileSys, "photos/trip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() == "beach.html" {
+			t.Errorf("Did not expect a page for media in a bundle")
+		}
+	}
+	var found bool
+	for _, f := range fileSys.dir("/photos/") {
+		if f.Filename == "trip/" {
+			found = f.FrontMatter.Title == "Trip" && f.Path == "/photos/"
+		}
+	}
+	if !found {
+		t.Errorf("Expected the bundle to be listed like a page: %#v", fileSys.dir("/photos/"))
+	}
+}
+
+func TestModTime(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	fileSys, err := New(fstest.MapFS{
+		"whisper.cfg":                  {Data: []byte(""), ModTime: day(2)},
+		"template/default.html":        {Data: []byte(`{{define "default"}}{{.Content}}{{end}}`), ModTime: day(3)},
+		"articles/how.md":              {Data: []byte("# How"), ModTime: day(4)},
+		"articles/old.md":              {Data: []byte("# Old"), ModTime: day(1)},
+		"notes/.defaults.toml":         {Data: []byte("description = \"Notes\""), ModTime: day(5)},
+		"notes/today.md":               {Data: []byte("# Today"), ModTime: day(1)},
+		"template/photos/default.html": {Data: []byte(`{{define "photos/default"}}{{end}}`), ModTime: day(1)},
+		"static/logo.png":              {Data: []byte("png"), ModTime: day(9)},
+		"sitemap.txt":                  {Data: []byte(`{{define "sitemap"}}{{range .}}{{.}} {{end}}{{end}}`), ModTime: day(1)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a page depends on its folder, which it may list, but not on other folders,
+	// while the site map lists the whole site
+	tests := map[string]time.Time{
+		"articles/how.html": day(4),
+		"articles/old.html": day(4),
+		"notes/today.html":  day(5),
+		"sitemap.txt":       day(9),
+	}
+	for name, expect := range tests {
+		for i := 0; i < 2; i++ {
+			fi, err := fs.Stat(fileSys, name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !fi.ModTime().Equal(expect) {
+				t.Errorf("Expected %v for %s but got %v", expect, name, fi.ModTime())
+			}
+		}
+	}
+}
+
+func TestRenderedSizes(t *testing.T) {
+	fileSys, err := New(fstest.MapFS{
+		"whisper.cfg":            {Data: []byte("languages = [\"en\", \"es\"]")},
+		"template/default.html":  {Data: []byte(`{{define "default"}}<main>{{.Content}}</main>{{end}}{{define "image"}}<img src="{{.FrontMatter.OriginalFile}}">{{end}}`)},
+		"about.md":               {Data: []byte("# About this site")},
+		"about.es.md":            {Data: []byte("# Acerca de")},
+		"sitemap.txt":            {Data: []byte(`{{define "sitemap"}}{{range .}}{{.}} {{end}}{{end}}`)},
+		"photos/cat.png":         {Data: []byte("png")},
+		"podcasts/episode-1.mp3": {Data: []byte("mp3")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, folder := range []string{".", "es", "photos", "podcasts"} {
+		entries, err := fs.ReadDir(fileSys, folder)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				t.Fatal(err)
+			}
+			fi, err := fs.Stat(fileSys, path.Join(folder, entry.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Size() != fi.Size() {
+				t.Errorf("Size of %s is %d in the listing but %d when opened", path.Join(folder, entry.Name()), info.Size(), fi.Size())
+			}
+			if !info.ModTime().Equal(fi.ModTime()) {
+				t.Errorf("Time of %s is %v in the listing but %v when opened", path.Join(folder, entry.Name()), info.ModTime(), fi.ModTime())
+			}
+		}
+	}
+}
+
+func TestPagesIndex(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	mfs := fstest.MapFS{
+		"template/default.html": {Data: []byte(`{{define "default"}}{{.Content}}{{end}}`)},
+		"a.md":                  {Data: []byte("+++\ntags = [\"go\"]\n+++\n# A"), ModTime: day(1)},
+	}
+	fileSys, err := New(mfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := fileSys.pages(); len(p) != 1 {
+		t.Fatalf("Expected one page but got %#v", p)
+	}
+	// pages are kept until the content is checked again and found to have changed
+	mfs["b.md"] = &fstest.MapFile{Data: []byte("+++\ntags = [\"go\"]\n+++\n# B"), ModTime: day(2)}
+	if p := fileSys.pages(); len(p) != 1 {
+		t.Errorf("Expected the pages to be kept but got %#v", p)
+	}
+	fileSys.index.checked = time.Time{}
+	if r := fileSys.related("/a.html", 5); len(r) != 1 || r[0].Filename != "b.html" {
+		t.Errorf("Expected the new page to be related but got %#v", r)
+	}
+}
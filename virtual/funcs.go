@@ -0,0 +1,381 @@
+package virtual
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// dateLayouts are names that may be used in place of a layout in the date function.
+var dateLayouts = map[string]string{
+	"rfc822":   time.RFC822,
+	"rfc1123":  time.RFC1123,
+	"rfc1123z": time.RFC1123Z,
+	"rfc3339":  time.RFC3339,
+	"kitchen":  time.Kitchen,
+	"date":     time.DateOnly,
+	"datetime": time.DateTime,
+}
+
+// date formats the time using the given layout, optionally converting it to the named
+// time zone first, like "America/New_York". It is used in templates.
+func date(layout string, t time.Time, zone ...string) (string, error) {
+	if len(zone) > 0 && zone[0] != "" {
+		loc, err := time.LoadLocation(zone[0])
+		if err != nil {
+			return "", fmt.Errorf("date: %w", err)
+		}
+		t = t.In(loc)
+	}
+	if l, ok := dateLayouts[strings.ToLower(layout)]; ok {
+		layout = l
+	}
+	return t.Format(layout), nil
+}
+
+// wordsPerMinute is the reading speed used to estimate reading time.
+const wordsPerMinute = 200
+
+// wordcount returns the number of words in the given text or HTML.
+func wordcount(s any) int {
+	return len(strings.Fields(plainText(s)))
+}
+
+// readingtime returns the estimated number of minutes needed to read the given text or HTML.
+func readingtime(s any) int {
+	words := wordcount(s)
+	if words == 0 {
+		return 0
+	}
+	return (words + wordsPerMinute - 1) / wordsPerMinute
+}
+
+// plainText returns the text of the given HTML without tags or entities.
+func plainText(s any) string {
+	var (
+		in  = toString(s)
+		out strings.Builder
+	)
+	for {
+		i := strings.IndexByte(in, '<')
+		if i < 0 {
+			out.WriteString(in)
+			break
+		}
+		out.WriteString(in[:i])
+		j := strings.IndexByte(in[i:], '>')
+		if j < 0 {
+			break
+		}
+		out.WriteByte(' ')
+		in = in[i+j+1:]
+	}
+	return html.UnescapeString(out.String())
+}
+
+// toString converts strings and HTML to a string.
+func toString(s any) string {
+	switch v := s.(type) {
+	case string:
+		return v
+	case template.HTML:
+		return string(v)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(s)
+}
+
+// voidElements are HTML elements that have no closing tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// truncatehtml shortens the HTML to about n characters of text, adding an ellipsis
+// when text was removed and closing any tags left open.
+func truncatehtml(n int, s any) template.HTML {
+	var (
+		in    = toString(s)
+		out   strings.Builder
+		open  []string
+		count int
+		i     int
+	)
+	for i < len(in) && count < n {
+		switch in[i] {
+		case '<':
+			j := strings.IndexByte(in[i:], '>')
+			if j < 0 {
+				i = len(in)
+				continue
+			}
+			tag := in[i : i+j+1]
+			out.WriteString(tag)
+			i += j + 1
+			name := tagName(tag)
+			switch {
+			case strings.HasPrefix(tag, "<!") || strings.HasSuffix(tag, "/>") || voidElements[name]:
+			case strings.HasPrefix(tag, "</"):
+				for k := len(open) - 1; k >= 0; k-- {
+					if open[k] == name {
+						open = open[:k]
+						break
+					}
+				}
+			default:
+				open = append(open, name)
+			}
+		case '&':
+			j := strings.IndexByte(in[i:], ';')
+			if j < 0 || j > 10 {
+				j = 0
+			}
+			out.WriteString(in[i : i+j+1])
+			i += j + 1
+			count++
+		default:
+			r, size := utf8.DecodeRuneInString(in[i:])
+			out.WriteRune(r)
+			i += size
+			count++
+		}
+	}
+	if strings.TrimSpace(plainText(in[i:])) != "" {
+		out.WriteString("…")
+	}
+	for k := len(open) - 1; k >= 0; k-- {
+		out.WriteString("</" + open[k] + ">")
+	}
+	return template.HTML(out.String())
+}
+
+// tagName returns the lowercase element name of an HTML tag like "<a href=...>".
+func tagName(tag string) string {
+	tag = strings.TrimLeft(strings.TrimPrefix(tag, "<"), "/")
+	end := strings.IndexFunc(tag, func(r rune) bool { return unicode.IsSpace(r) || r == '>' || r == '/' })
+	if end >= 0 {
+		tag = tag[:end]
+	}
+	return strings.ToLower(tag)
+}
+
+// moreSeparator marks the end of the summary in Markdown.
+const moreSeparator = "<!--more-->"
+
+// summaryLength is the length of a summary when there is no separator or paragraph.
+const summaryLength = 200
+
+// summary returns the HTML before a "<!--more-->" comment, or else the first paragraph,
+// for use in listings.
+func summary(s any) template.HTML {
+	in := toString(s)
+	if i := strings.Index(in, moreSeparator); i >= 0 {
+		return template.HTML(strings.TrimSpace(in[:i]))
+	}
+	if i := strings.Index(in, "<p>"); i >= 0 {
+		if j := strings.Index(in[i:], "</p>"); j >= 0 {
+			return template.HTML(in[i : i+j+len("</p>")])
+		}
+	}
+	return truncatehtml(summaryLength, in)
+}
+
+// slugify converts the string into a form suitable for a URL, like "hello-world".
+func slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// frontMatterField returns the named front matter field, matching either the
+// field name or the TOML name without regard to case.
+func frontMatterField(fm *FrontMatter, name string) (reflect.Value, bool) {
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "."), "FrontMatter.")
+	v := reflect.ValueOf(fm).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+		if strings.EqualFold(f.Name, name) || strings.EqualFold(tag, name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// where returns the files whose front matter field matches the value. For lists
+// like tags, the file matches when any element matches.
+func where(f []File, field string, value any) ([]File, error) {
+	var r []File
+	want := fmt.Sprint(value)
+	for i := range f {
+		v, ok := frontMatterField(&f[i].FrontMatter, field)
+		if !ok {
+			return nil, fmt.Errorf("where: unknown field %q", field)
+		}
+		if v.Kind() == reflect.Slice {
+			for j := 0; j < v.Len(); j++ {
+				if fmt.Sprint(v.Index(j).Interface()) == want {
+					r = append(r, f[i])
+					break
+				}
+			}
+		} else if fmt.Sprint(v.Interface()) == want {
+			r = append(r, f[i])
+		}
+	}
+	return r, nil
+}
+
+// sortby sorts a copy of the files by the given front matter field, in ascending
+// order unless the order is "desc".
+func sortby(f []File, field string, order ...string) ([]File, error) {
+	if len(f) > 0 {
+		if _, ok := frontMatterField(&f[0].FrontMatter, field); !ok {
+			return nil, fmt.Errorf("sortby: unknown field %q", field)
+		}
+	}
+	desc := len(order) > 0 && strings.EqualFold(order[0], "desc")
+	r := make([]File, len(f))
+	copy(r, f)
+	sort.SliceStable(r, func(i, j int) bool {
+		a, _ := frontMatterField(&r[i].FrontMatter, field)
+		b, _ := frontMatterField(&r[j].FrontMatter, field)
+		if desc {
+			a, b = b, a
+		}
+		return less(a, b)
+	})
+	return r, nil
+}
+
+// less compares two front matter values of the same type.
+func less(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Int, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	}
+	if t, ok := a.Interface().(time.Time); ok {
+		return t.Before(b.Interface().(time.Time))
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+// first returns the first item of a list, or nil if it is empty.
+func first(list any) (any, error) {
+	v, err := listValue("first", list)
+	if err != nil || v.Len() == 0 {
+		return nil, err
+	}
+	return v.Index(0).Interface(), nil
+}
+
+// last returns the last item of a list, or nil if it is empty.
+func last(list any) (any, error) {
+	v, err := listValue("last", list)
+	if err != nil || v.Len() == 0 {
+		return nil, err
+	}
+	return v.Index(v.Len() - 1).Interface(), nil
+}
+
+// limit returns at most the first n items of a list.
+func limit(n int, list any) (any, error) {
+	v, err := listValue("limit", list)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		n = 0
+	}
+	if n < v.Len() {
+		v = v.Slice(0, n)
+	}
+	return v.Interface(), nil
+}
+
+// listValue checks that list is a slice or array.
+func listValue(fn string, list any) (reflect.Value, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return v, fmt.Errorf("%s: cannot use %T as a list", fn, list)
+	}
+	return v, nil
+}
+
+func add(a, b int) int { return a + b }
+func sub(a, b int) int { return a - b }
+func mul(a, b int) int { return a * b }
+
+// div divides a by b, which is an error if b is zero.
+func div(a, b int) (int, error) {
+	if b == 0 {
+		return 0, errors.New("div: division by zero")
+	}
+	return a / b, nil
+}
+
+// mod returns the remainder of a divided by b, which is an error if b is zero.
+func mod(a, b int) (int, error) {
+	if b == 0 {
+		return 0, errors.New("mod: division by zero")
+	}
+	return a % b, nil
+}
+
+// dict makes a map from pairs of keys and values, like (dict "title" .Title "size" 10).
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict: expected pairs of keys and values")
+	}
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		k, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key %v is not a string", pairs[i])
+		}
+		m[k] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// slice makes a list from its arguments, like (slice "a" "b" "c").
+// It replaces the built-in template function of the same name.
+func slice(items ...any) []any {
+	return items
+}
+
+// absurl returns the absolute URL of the given path using the configured base URL.
+func (vfs *FS) absurl(p string) string {
+	if strings.Contains(p, "://") {
+		return p
+	}
+	var base string
+	if cfg := vfs.config(); cfg != nil {
+		base = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	return base + "/" + strings.TrimPrefix(p, "/")
+}
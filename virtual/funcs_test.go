@@ -0,0 +1,105 @@
+package virtual
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDate(t *testing.T) {
+	tm := time.Date(2024, 3, 5, 15, 4, 0, 0, time.UTC)
+	s, err := date("Jan 2, 2006 15:04", tm)
+	if err != nil || s != "Mar 5, 2024 15:04" {
+		t.Errorf("Unexpected date %q: %v", s, err)
+	}
+	s, err = date("rfc3339", tm, "America/New_York")
+	if err != nil || s != "2024-03-05T10:04:00-05:00" {
+		t.Errorf("Unexpected date %q: %v", s, err)
+	}
+	if _, err = date("date", tm, "Nowhere/Special"); err == nil {
+		t.Errorf("Expected error for unknown zone")
+	}
+}
+
+func TestTextFuncs(t *testing.T) {
+	h := template.HTML("<p>One <em>two</em> three &amp; four</p>")
+	if n := wordcount(h); n != 5 {
+		t.Errorf("Expected 5 words but got %d", n)
+	}
+	if n := readingtime(strings.Repeat("word ", 201)); n != 2 {
+		t.Errorf("Expected 2 minutes but got %d", n)
+	}
+	if s := truncatehtml(5, h); s != "<p>One <em>t…</em></p>" {
+		t.Errorf("Unexpected truncated HTML %q", s)
+	}
+	if s := truncatehtml(100, h); s != h {
+		t.Errorf("Expected short HTML unchanged but got %q", s)
+	}
+	if s := summary("<p>First</p>\n<p>Second</p>"); s != "<p>First</p>" {
+		t.Errorf("Unexpected summary %q", s)
+	}
+	if s := summary("<p>First</p>\n<p>Second</p>\n<!--more-->\n<p>Third</p>"); s != "<p>First</p>\n<p>Second</p>" {
+		t.Errorf("Unexpected summary %q", s)
+	}
+	if s := slugify("  Hello, Wörld! 2024 "); s != "hello-wörld-2024" {
+		t.Errorf("Unexpected slug %q", s)
+	}
+}
+
+func TestListFuncs(t *testing.T) {
+	files := []File{
+		{Filename: "a.html", FrontMatter: FrontMatter{Title: "B", Weight: 2, Tags: []string{"x"}}},
+		{Filename: "b.html", FrontMatter: FrontMatter{Title: "A", Weight: 3, Tags: []string{"x", "y"}}},
+		{Filename: "c.html", FrontMatter: FrontMatter{Title: "C", Weight: 1, Series: "s"}},
+	}
+	r, err := where(files, "tags", "y")
+	if err != nil || len(r) != 1 || r[0].Filename != "b.html" {
+		t.Errorf("Unexpected where result %v: %v", r, err)
+	}
+	r, err = where(files, "Series", "s")
+	if err != nil || len(r) != 1 || r[0].Filename != "c.html" {
+		t.Errorf("Unexpected where result %v: %v", r, err)
+	}
+	if _, err = where(files, "nope", "s"); err == nil {
+		t.Errorf("Expected error for unknown field")
+	}
+	r, err = sortby(files, "title")
+	if err != nil || r[0].Filename != "b.html" || r[2].Filename != "c.html" || files[0].Filename != "a.html" {
+		t.Errorf("Unexpected sortby result %v: %v", r, err)
+	}
+	r, err = sortby(files, "weight", "desc")
+	if err != nil || r[0].Filename != "b.html" || r[2].Filename != "c.html" {
+		t.Errorf("Unexpected sortby result %v: %v", r, err)
+	}
+	if v, err := first(files); err != nil || v.(File).Filename != "a.html" {
+		t.Errorf("Unexpected first %v: %v", v, err)
+	}
+	if v, err := last(files); err != nil || v.(File).Filename != "c.html" {
+		t.Errorf("Unexpected last %v: %v", v, err)
+	}
+	if v, err := limit(2, files); err != nil || len(v.([]File)) != 2 {
+		t.Errorf("Unexpected limit %v: %v", v, err)
+	}
+	if _, err := first(3); err == nil {
+		t.Errorf("Expected error for non-list")
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	var vfs FS
+	vfs.cfg = &Config{BaseURL: "https://example.com/"}
+	tpl := template.Must(template.New("t").Funcs(template.FuncMap{
+		"dict": dict, "slice": slice, "add": add, "div": div, "limit": limit, "absurl": vfs.absurl,
+	}).Parse(`{{with dict "a" 1 "b" "x"}}{{.b}}{{end}} {{range limit 2 (slice "p" "q" "r")}}{{.}}{{end}} {{add 2 (div 9 3)}} {{absurl "/about.html"}}`))
+	var b strings.Builder
+	if err := tpl.Execute(&b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); s != "x pq 5 https://example.com/about.html" {
+		t.Errorf("Unexpected output %q", s)
+	}
+	if _, err := div(1, 0); err == nil {
+		t.Errorf("Expected division by zero error")
+	}
+}
@@ -6,12 +6,18 @@ import (
 
 var hiddenFiles = []string{
 	"template",
+	dataFolder,
 	"whisper.cfg",
+	redirectsFile,
 }
 
 // isHiddenFile returns true if the given file is considered
-// hidden from outside view.
+// hidden from outside view. The contents of the data folder are
+// hidden too.
 func isHiddenFile(name string) bool {
+	if strings.HasPrefix(name, dataFolder+"/") {
+		return true
+	}
 	for _, s := range hiddenFiles {
 		if name == s {
 			return true
@@ -20,6 +26,11 @@ func isHiddenFile(name string) bool {
 	return false
 }
 
+// isSitemap returns true if the file is a sitemap template.
+func isSitemap(name string) bool {
+	return name == "sitemap.txt" || name == "sitemap.xml"
+}
+
 // containsSpecialFile reports whether name contains a path element starting with a period
 // or is another kind of special file. The name is assumed to be a delimited by forward
 // slashes, as guaranteed by the fs.FS interface.
@@ -35,7 +46,7 @@ func containsSpecialFile(name string) bool {
 
 // hasMediaFolderPrefix checks if the entry is in an image folder.
 func hasMediaFolderPrefix(s string) bool {
-	imageFolders := []string{"photos", "images", "pictures", "cartoons", "toons", "sketches", "artwork", "drawings", "videos", "movies"}
+	imageFolders := []string{"photos", "images", "pictures", "cartoons", "toons", "sketches", "artwork", "drawings", "videos", "movies", "audio", "music", "podcasts"}
 	for _, f := range imageFolders {
 		if strings.HasPrefix(s, f) {
 			return true
@@ -44,10 +55,25 @@ func hasMediaFolderPrefix(s string) bool {
 	return false
 }
 
-// hasMediaExtension checks if the path ends in an image type.
+// mediaExtensions lists the extensions of files that get virtual pages in media folders.
+// Video and audio come first so that an image with the same name becomes their poster
+// instead of its own page.
+var mediaExtensions = []string{".mp4", ".mov", ".webm", ".mp3", ".ogg", ".m4a", ".flac", ".png", ".jpg", ".gif", ".webp", ".jpeg"}
+
+// hasMediaExtension checks if the path ends in a media type.
 func hasMediaExtension(s string) bool {
-	imageTypes := []string{".png", ".jpg", ".gif", ".webp", ".jpeg", ".mp4", ".mov", ".webm"}
-	for _, ext := range imageTypes {
+	for _, ext := range mediaExtensions {
+		if strings.HasSuffix(s, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAudioExtension checks if the path ends in an audio type.
+func hasAudioExtension(s string) bool {
+	audioTypes := []string{".mp3", ".ogg", ".m4a", ".flac"}
+	for _, ext := range audioTypes {
 		if strings.HasSuffix(s, ext) {
 			return true
 		}
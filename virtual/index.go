@@ -0,0 +1,96 @@
+package virtual
+
+import (
+	"io/fs"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contentCheckInterval is how often the site is checked for changed content.
+const contentCheckInterval = 2 * time.Second
+
+// siteIndex holds what is known about the content of the whole site. It is kept
+// until the content changes, so that pages that depend on other pages don't each
+// have to walk the site.
+type siteIndex struct {
+	mutex   sync.Mutex
+	checked time.Time        // when the content was last checked
+	walking bool             // whether the content is being checked
+	modTime time.Time        // latest modification time of the content
+	count   int              // number of files and folders, which changes when one is removed
+	key     [2]time.Time     // content and template times that the values below were found for
+	pages   []File           // Markdown pages of the site, if found
+	paged   bool             // whether pages was found
+	trees   map[string]*Node // folder trees by folder
+}
+
+// indexKey returns the content and template times that the index values depend on.
+func (vfs *FS) indexKey() [2]time.Time {
+	return [2]time.Time{vfs.contentModTime(), vfs.templateModTime()}
+}
+
+// update clears the values found for an older key. The caller must hold the mutex.
+func (idx *siteIndex) update(key [2]time.Time) {
+	if idx.key != key {
+		idx.key = key
+		idx.pages, idx.paged = nil, false
+		idx.trees = nil
+	}
+}
+
+// contentModTime returns the latest modification time of the files and folders of
+// the site, other than the templates and data files. It tells when the pages found
+// for functions like related, series, and tree, and the site map, need to be found
+// again. The site is walked at most every contentCheckInterval, without holding the
+// lock, so callers meanwhile get the time found by the last walk.
+func (vfs *FS) contentModTime() time.Time {
+	idx := &vfs.index
+	idx.mutex.Lock()
+	if idx.walking || time.Since(idx.checked) < contentCheckInterval {
+		t := idx.modTime
+		idx.mutex.Unlock()
+		return t
+	}
+	idx.walking = true
+	idx.mutex.Unlock()
+
+	var (
+		t     time.Time
+		count int
+	)
+	err := fs.WalkDir(vfs.fs, ".", func(pathname string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && (pathname == "template" || pathname == dataFolder || (pathname != "." && strings.HasPrefix(d.Name(), "."))) {
+			return fs.SkipDir
+		}
+		if !d.IsDir() && strings.HasPrefix(d.Name(), ".") && d.Name() != defaultsFile {
+			return nil
+		}
+		count++
+		if fi, err := d.Info(); err == nil {
+			t = latest(t, fi.ModTime())
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("contentModTime cannot read site", "error", err)
+	}
+	now := time.Now()
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	switch {
+	case idx.checked.IsZero():
+		idx.modTime = t
+	case count != idx.count || t.Before(idx.modTime):
+		// a file was removed or replaced by an older one, which is a change now
+		idx.modTime = latest(t, now)
+	default:
+		idx.modTime = t
+	}
+	idx.checked, idx.count, idx.walking = now, count, false
+	return idx.modTime
+}
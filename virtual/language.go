@@ -0,0 +1,241 @@
+package virtual
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Translation identifies the version of a page in one language.
+type Translation struct {
+	Language string // language code, like "es"
+	URL      string // path of the page, like "/es/about.html"
+}
+
+// languages returns the configured languages, starting with the default.
+func (vfs *FS) languages() []string {
+	if cfg := vfs.config(); cfg != nil {
+		return cfg.Languages
+	}
+	return nil
+}
+
+// splitLanguage checks if the first element of name is a configured language,
+// returning the language and the rest of the name.
+func (vfs *FS) splitLanguage(name string) (string, string, bool) {
+	first, rest, _ := strings.Cut(name, "/")
+	if !slices.Contains(vfs.languages(), first) {
+		return "", "", false
+	}
+	if rest == "" {
+		rest = "."
+	}
+	return first, rest, true
+}
+
+// variantLanguage returns the language of a Markdown variant like "about.es.md",
+// or an empty string if name is not a variant.
+func (vfs *FS) variantLanguage(name string) string {
+	if path.Ext(name) != ".md" {
+		return ""
+	}
+	lang := strings.TrimPrefix(path.Ext(strings.TrimSuffix(name, ".md")), ".")
+	if langs := vfs.languages(); lang != "" && len(langs) > 1 && slices.Contains(langs[1:], lang) {
+		return lang
+	}
+	return ""
+}
+
+// variantName returns the name of the Markdown variant of md for the given language.
+// The default language uses md itself.
+func (vfs *FS) variantName(md, lang string) string {
+	if langs := vfs.languages(); len(langs) == 0 || lang == langs[0] {
+		return md
+	}
+	return strings.TrimSuffix(md, ".md") + "." + lang + ".md"
+}
+
+// pageLanguage returns the language of the page at pathname.
+func (vfs *FS) pageLanguage(pathname string) string {
+	if lang, _, ok := vfs.splitLanguage(strings.TrimPrefix(pathname, "/")); ok {
+		return lang
+	}
+	if langs := vfs.languages(); len(langs) > 0 {
+		return langs[0]
+	}
+	return ""
+}
+
+// markdownFor returns the Markdown file used to render the page at pathname.
+func (vfs *FS) markdownFor(pathname string) string {
+	pathname = strings.TrimPrefix(pathname, "/")
+	md := strings.TrimSuffix(pathname, path.Ext(pathname)) + ".md"
+	if lang, rest, ok := vfs.splitLanguage(pathname); ok {
+		if _, err := fs.Stat(vfs.fs, md); err != nil {
+			md = vfs.variantName(strings.TrimSuffix(rest, path.Ext(rest))+".md", lang)
+		}
+	}
+	return md
+}
+
+// translations returns the versions of the page at pathname in each configured
+// language, including the page itself, and is used in templates.
+func (vfs *FS) translations(pathname string) []Translation {
+	langs := vfs.languages()
+	if len(langs) == 0 {
+		return nil
+	}
+	pathname = strings.TrimPrefix(pathname, "/")
+	if strings.HasSuffix(pathname, "/") || pathname == "" {
+		pathname += "index.html"
+	}
+	if _, rest, ok := vfs.splitLanguage(pathname); ok {
+		pathname = rest
+	}
+	md := strings.TrimSuffix(pathname, path.Ext(pathname)) + ".md"
+	var t []Translation
+	for i, lang := range langs {
+		if _, err := fs.Stat(vfs.fs, vfs.variantName(md, lang)); err != nil {
+			continue
+		}
+		url := "/" + pathname
+		if i > 0 {
+			url = "/" + lang + url
+		}
+		t = append(t, Translation{Language: lang, URL: strings.TrimSuffix(url, "index.html")})
+	}
+	return t
+}
+
+// openLanguage opens a name starting with a language folder, like "es/about.html".
+// Pages are rendered from the Markdown variant for the language, and folders
+// list the pages that have been translated.
+func (vfs *FS) openLanguage(name, lang, rest string) (fs.File, error) {
+	notExist := &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	if rest != "." && (isHiddenFile(rest) || containsSpecialFile(rest)) {
+		return nil, notExist
+	}
+	if fi, err := fs.Stat(vfs.fs, rest); err == nil && fi.IsDir() {
+		return vfs.newLanguageDirectory(name, lang, rest)
+	}
+	if path.Ext(rest) != ".html" {
+		return nil, notExist
+	}
+	var (
+		candidates = []string{strings.TrimSuffix(rest, ".html") + ".md"}
+		number     = 1
+	)
+	if base, n, ok := splitPageName(rest); ok {
+		candidates, number = []string{path.Join(base, "index.md"), base + ".md"}, n
+	}
+	for _, md := range candidates {
+		f, err := vfs.fs.Open(vfs.variantName(md, lang))
+		if err == nil {
+			defer f.Close()
+			pathname := path.Join(lang, strings.TrimSuffix(md, ".md")+".html")
+			page, err := vfs.newMarkdownPage(f, pathname, number)
+			if errors.Is(err, fs.ErrNotExist) {
+				// the listing has fewer pages
+				return nil, notExist
+			}
+			return page, err
+		}
+	}
+	return nil, notExist
+}
+
+// hasVariants checks if the folder holds any pages in the given language.
+func (vfs *FS) hasVariants(folder, lang string) bool {
+	entries, err := fs.ReadDir(vfs.fs, folder)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		nm := entry.Name()
+		if !entry.IsDir() && !containsSpecialFile(nm) && vfs.variantLanguage(nm) == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// newLanguageDirectory lists the translated pages in a folder, and any
+// subfolders that have translated pages.
+func (vfs *FS) newLanguageDirectory(name, lang, rest string) (fs.File, error) {
+	fi, err := fs.Stat(vfs.fs, rest)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := fs.ReadDir(vfs.fs, rest)
+	if err != nil {
+		return nil, err
+	}
+	langs := vfs.languages()
+	deps := vfs.folderModTime(rest)
+	var vEntries []fs.DirEntry
+	for _, entry := range entries {
+		nm := entry.Name()
+		if containsSpecialFile(nm) || (rest == "." && isHiddenFile(nm)) {
+			continue
+		}
+		if entry.IsDir() {
+			if lang == langs[0] || vfs.hasVariants(path.Join(rest, nm), lang) {
+				vEntries = append(vEntries, entry)
+			}
+			continue
+		}
+		// pages in the default language are not variants
+		want := lang
+		if lang == langs[0] {
+			want = ""
+		}
+		if path.Ext(nm) != ".md" || vfs.variantLanguage(nm) != want {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		newNm := strings.TrimSuffix(nm, ".md")
+		if lang != langs[0] {
+			newNm = strings.TrimSuffix(newNm, "."+lang)
+		}
+		newNm += ".html"
+		vEntries = append(vEntries, vfs.renderedEntry(path.Join(name, newNm), info, deps))
+	}
+	sort.Slice(vEntries, func(i, j int) bool {
+		return vEntries[i].Name() < vEntries[j].Name()
+	})
+	return &virtualDir{
+		fi: fileInfo{
+			nm: path.Base(name),
+			sz: fi.Size(),
+			md: fi.Mode(),
+			mt: fi.ModTime(),
+		},
+		entries: vEntries,
+	}, nil
+}
+
+// languageEntries returns folder entries for the languages other than the
+// default that have pages in the root folder.
+func (vfs *FS) languageEntries(added map[string]bool) []fs.DirEntry {
+	langs := vfs.languages()
+	if len(langs) < 2 {
+		return nil
+	}
+	fi, err := fs.Stat(vfs.fs, ".")
+	if err != nil {
+		return nil
+	}
+	var r []fs.DirEntry
+	for _, lang := range langs[1:] {
+		if !added[lang] && vfs.hasVariants(".", lang) {
+			r = append(r, fs.FileInfoToDirEntry(fileInfo{nm: lang, md: fi.Mode(), mt: fi.ModTime()}))
+		}
+	}
+	return r
+}
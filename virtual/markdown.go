@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/pelletier/go-toml/v2"
-	"github.com/russross/blackfriday/v2"
 )
 
 // pathToMarkdown takes a URL path and converts it into the path to the associated Markdown file.
@@ -28,13 +27,14 @@ func pathToMarkdown(filename string) string {
 }
 
 // renderMarkdown renders the markdown for the given file and returns the frontmatter.
-func (vfs *FS) renderMarkdown(filename string) (*FrontMatter, template.HTML, time.Time, error) {
+// The depth counts how many pages are being rendered into each other.
+func (vfs *FS) renderMarkdown(filename string, depth int) (*FrontMatter, template.HTML, time.Time, error) {
 	var (
 		fmData  FrontMatter
 		md      template.HTML
 		modTime time.Time
 	)
-	filename = pathToMarkdown(filename)
+	filename = vfs.markdownFor(pathToMarkdown(filename))
 	s, err := fs.Stat(vfs.fs, filename)
 	if err != nil {
 		return nil, "", modTime, fmt.Errorf("renderMarkdown: %w", err)
@@ -44,32 +44,52 @@ func (vfs *FS) renderMarkdown(filename string) (*FrontMatter, template.HTML, tim
 		return nil, "", modTime, fmt.Errorf("renderMarkdown: %w", err)
 	}
 	fm, r := extractFrontMatter(b)
-	md = template.HTML(blackfriday.Run(r, blackfriday.WithExtensions(blackfriday.CommonExtensions|blackfriday.Footnotes)))
+	err = vfs.readDefaults(path.Dir(filename), &fmData)
+	if err != nil {
+		return nil, "", modTime, fmt.Errorf("renderMarkdown: %w", err)
+	}
 	if len(fm) > 0 {
 		err = toml.Unmarshal(fm, &fmData)
 		if err != nil {
 			return nil, "", modTime, fmt.Errorf("renderMarkdown: %w", err)
 		}
 	}
+	p, bn := path.Split(filename)
+	md = vfs.renderContent(r, data{
+		FrontMatter: fmData,
+		Page:        PageInfo{Path: "/" + p, Filename: strings.TrimSuffix(bn, path.Ext(bn)) + ".html", Number: 1},
+		Site:        vfs.site(),
+		depth:       depth,
+	})
 	return &fmData, md, s.ModTime(), nil
 }
 
-// md convert the given markdown file to HTML and is used in templates.
-func (vfs *FS) md(filename string) template.HTML {
-	_, md, _, err := vfs.renderMarkdown(filename)
-	if err != nil {
-		slog.Error("md failed:", "error", err)
-		return ""
+// markdownAt returns the function that converts the given markdown file to HTML
+// for templates rendering at the given depth. Pages nested deeper than maxDepth,
+// like a templated page that includes itself, are left out.
+func (vfs *FS) markdownAt(depth int) func(filename string) template.HTML {
+	return func(filename string) template.HTML {
+		if depth >= maxDepth {
+			slog.Error("md failed: pages nested too deeply", "file", filename)
+			return ""
+		}
+		_, md, _, err := vfs.renderMarkdown(filename, depth+1)
+		if err != nil {
+			slog.Error("md failed:", "error", err)
+			return ""
+		}
+		return md
 	}
-	return md
 }
 
-// fm returns front matter for the given file and is used in templates.
+// fm returns front matter for the given file and is used in templates. The body
+// is not rendered, so a page may read its own front matter.
 func (vfs *FS) fm(filename string) *FrontMatter {
-	fm, _, _, err := vfs.renderMarkdown(filename)
+	var fmData FrontMatter
+	err := vfs.readFrontMatter(vfs.markdownFor(pathToMarkdown(filename)), &fmData)
 	if err != nil {
 		slog.Error("fm failed", "error", err)
 		return nil
 	}
-	return fm
+	return &fmData
 }
@@ -0,0 +1,787 @@
+package virtual
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// MediaInfo holds metadata extracted from an audio or video file.
+type MediaInfo struct {
+	MimeType string        // MIME type of the media file
+	Size     int64         // Size of the media file in bytes
+	Duration time.Duration // Play time, if known
+	Width    int           // Width of video in pixels, if known
+	Height   int           // Height of video in pixels, if known
+	Poster   string        // Image file with the same base name, used as poster frame or cover art
+	Title    string        // Title tag
+	Artist   string        // Artist tag
+	Album    string        // Album tag
+	Year     string        // Year or recording date tag
+	Genre    string        // Genre tag
+	Track    string        // Track number tag
+	Comment  string        // Comment tag
+}
+
+// Clock formats the duration as H:MM:SS or M:SS, which is what podcast
+// clients expect.
+func (m MediaInfo) Clock() string {
+	s := int64(m.Duration.Round(time.Second) / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, (s/60)%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// mediaTypes maps media extensions to MIME types.
+var mediaTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".mp4":  "video/mp4",
+	".mov":  "video/quicktime",
+	".webm": "video/webm",
+	".mp3":  "audio/mpeg",
+	".ogg":  "audio/ogg",
+	".m4a":  "audio/mp4",
+	".flac": "audio/flac",
+}
+
+// maxTagSize limits how much of a file is read when looking for tags.
+const maxTagSize = 16 * 1024 * 1024
+
+// readMediaInfo extracts what metadata it can from the media file.
+// Failures are not errors; the returned MediaInfo is simply less complete.
+func readMediaInfo(f fs.File) MediaInfo {
+	var m MediaInfo
+	fi, err := f.Stat()
+	if err != nil {
+		return m
+	}
+	ext := strings.ToLower(path.Ext(fi.Name()))
+	m.MimeType = mediaTypes[ext]
+	m.Size = fi.Size()
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		return m
+	}
+	switch ext {
+	case ".mp3":
+		readMP3Info(rs, &m)
+	case ".flac":
+		readFLACInfo(rs, &m)
+	case ".ogg":
+		readOggInfo(rs, &m)
+	case ".m4a", ".mp4", ".mov":
+		readMP4Info(rs, &m)
+	case ".webm":
+		readWebMInfo(rs, &m)
+	}
+	return m
+}
+
+// readAt reads up to n bytes at the given offset.
+func readAt(rs io.ReadSeeker, offset int64, n int) []byte {
+	if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+		return nil
+	}
+	b := make([]byte, n)
+	n, _ = io.ReadFull(rs, b)
+	return b[:n]
+}
+
+// readID3v2 parses an ID3v2 tag at the start of the file, returning the tag size
+// (so the audio that follows can be found) and the duration from TLEN, if any.
+func readID3v2(rs io.ReadSeeker, m *MediaInfo) (int64, time.Duration) {
+	hdr := readAt(rs, 0, 10)
+	if len(hdr) < 10 || string(hdr[:3]) != "ID3" {
+		return 0, 0
+	}
+	ver, flags := hdr[3], hdr[5]
+	size := int64(syncsafe(hdr[6:10]))
+	total := size + 10
+	if flags&0x10 != 0 {
+		total += 10 // footer
+	}
+	if size > maxTagSize {
+		return total, 0
+	}
+	b := readAt(rs, 10, int(size))
+	if flags&0x40 != 0 && ver >= 3 && len(b) >= 4 {
+		// skip extended header
+		n := int(binary.BigEndian.Uint32(b))
+		if ver == 4 {
+			n = int(syncsafe(b[:4]))
+		} else {
+			n += 4
+		}
+		if n > len(b) {
+			return total, 0
+		}
+		b = b[n:]
+	}
+
+	var tlen time.Duration
+	idLen, hdrLen := 4, 10
+	if ver == 2 {
+		idLen, hdrLen = 3, 6
+	}
+	for len(b) >= hdrLen && b[0] != 0 {
+		id := string(b[:idLen])
+		var n int
+		switch ver {
+		case 2:
+			n = int(b[3])<<16 | int(b[4])<<8 | int(b[5])
+		case 4:
+			n = int(syncsafe(b[4:8]))
+		default:
+			n = int(binary.BigEndian.Uint32(b[4:8]))
+		}
+		if n < 0 || hdrLen+n > len(b) {
+			break
+		}
+		body := b[hdrLen : hdrLen+n]
+		b = b[hdrLen+n:]
+		switch id {
+		case "TIT2", "TT2":
+			m.Title = id3Text(body)
+		case "TPE1", "TP1":
+			m.Artist = id3Text(body)
+		case "TALB", "TAL":
+			m.Album = id3Text(body)
+		case "TYER", "TYE", "TDRC":
+			m.Year = id3Text(body)
+		case "TCON", "TCO":
+			m.Genre = id3Text(body)
+		case "TRCK", "TRK":
+			m.Track = id3Text(body)
+		case "TLEN", "TLE":
+			var ms int64
+			if _, err := fmt.Sscan(id3Text(body), &ms); err == nil {
+				tlen = time.Duration(ms) * time.Millisecond
+			}
+		case "COMM", "COM":
+			// encoding, language, short description, text
+			if len(body) > 4 && m.Comment == "" {
+				parts := splitID3Strings(body[0], body[4:])
+				if len(parts) > 1 {
+					m.Comment = parts[1]
+				}
+			}
+		}
+	}
+	return total, tlen
+}
+
+// syncsafe decodes a 28-bit ID3 "syncsafe" integer.
+func syncsafe(b []byte) uint32 {
+	return uint32(b[0]&0x7f)<<21 | uint32(b[1]&0x7f)<<14 | uint32(b[2]&0x7f)<<7 | uint32(b[3]&0x7f)
+}
+
+// id3Text decodes an ID3 text frame body.
+func id3Text(body []byte) string {
+	if len(body) < 1 {
+		return ""
+	}
+	parts := splitID3Strings(body[0], body[1:])
+	if len(parts) == 0 {
+		return ""
+	}
+	return parts[0]
+}
+
+// splitID3Strings decodes the null-separated strings of the given ID3 encoding.
+func splitID3Strings(enc byte, b []byte) []string {
+	var r []string
+	switch enc {
+	case 1, 2: // UTF-16 with BOM, UTF-16BE
+		var order binary.ByteOrder = binary.BigEndian
+		var u []uint16
+		for i := 0; i+1 < len(b); i += 2 {
+			c := order.Uint16(b[i:])
+			switch {
+			case c == 0xfeff && enc == 1 && len(u) == 0:
+				continue
+			case c == 0xfffe && enc == 1 && len(u) == 0:
+				order = binary.LittleEndian
+				continue
+			case c == 0:
+				r = append(r, strings.TrimSpace(string(utf16.Decode(u))))
+				u = u[:0]
+				continue
+			}
+			u = append(u, c)
+		}
+		if len(u) > 0 {
+			r = append(r, strings.TrimSpace(string(utf16.Decode(u))))
+		}
+	case 3: // UTF-8
+		for _, s := range bytes.Split(b, []byte{0}) {
+			r = append(r, strings.TrimSpace(string(s)))
+		}
+	default: // ISO-8859-1
+		for _, s := range bytes.Split(b, []byte{0}) {
+			r = append(r, latin1(s))
+		}
+	}
+	return r
+}
+
+// latin1 converts ISO-8859-1 bytes to a string.
+func latin1(b []byte) string {
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return strings.TrimSpace(string(r))
+}
+
+// readID3v1 fills in any missing tags from an ID3v1 tag at the end of the file.
+// It returns true if the tag was present.
+func readID3v1(rs io.ReadSeeker, size int64, m *MediaInfo) bool {
+	if size < 128 {
+		return false
+	}
+	b := readAt(rs, size-128, 128)
+	if len(b) < 128 || string(b[:3]) != "TAG" {
+		return false
+	}
+	field := func(s []byte) string {
+		if i := bytes.IndexByte(s, 0); i >= 0 {
+			s = s[:i]
+		}
+		return latin1(s)
+	}
+	set := func(dst *string, v string) {
+		if *dst == "" {
+			*dst = v
+		}
+	}
+	set(&m.Title, field(b[3:33]))
+	set(&m.Artist, field(b[33:63]))
+	set(&m.Album, field(b[63:93]))
+	set(&m.Year, field(b[93:97]))
+	set(&m.Comment, field(b[97:127]))
+	if b[125] == 0 && b[126] != 0 {
+		set(&m.Track, fmt.Sprint(b[126]))
+	}
+	return true
+}
+
+// MPEG audio lookup tables, in kbps and Hz.
+var (
+	mpegBitrates = [5][15]int{
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448}, // V1 L1
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},    // V1 L2
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},     // V1 L3
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},    // V2 L1
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},         // V2 L2 & L3
+	}
+	mpegSampleRates = [3]int{44100, 48000, 32000}
+)
+
+// readMP3Info reads tags and computes the duration of an MP3 file.
+func readMP3Info(rs io.ReadSeeker, m *MediaInfo) {
+	start, tlen := readID3v2(rs, m)
+	end := m.Size
+	if readID3v1(rs, m.Size, m) {
+		end -= 128
+	}
+	if tlen > 0 {
+		m.Duration = tlen
+		return
+	}
+
+	// find the first frame
+	b := readAt(rs, start, 64*1024)
+	for i := 0; i+4 <= len(b); i++ {
+		if b[i] != 0xff || b[i+1]&0xe0 != 0xe0 {
+			continue
+		}
+		version := (b[i+1] >> 3) & 0x03 // 0 = 2.5, 2 = 2, 3 = 1
+		layer := (b[i+1] >> 1) & 0x03   // 1 = III, 2 = II, 3 = I
+		brIndex := b[i+2] >> 4
+		srIndex := (b[i+2] >> 2) & 0x03
+		if version == 1 || layer == 0 || brIndex == 0 || brIndex == 15 || srIndex == 3 {
+			continue
+		}
+		var table, samples int
+		switch {
+		case version == 3:
+			table = int(3 - layer)
+		case layer == 3:
+			table = 3
+		default:
+			table = 4
+		}
+		switch {
+		case layer == 3:
+			samples = 384
+		case layer == 2 || version == 3:
+			samples = 1152
+		default:
+			samples = 576
+		}
+		bitrate := mpegBitrates[table][brIndex] * 1000
+		rate := mpegSampleRates[srIndex]
+		switch version {
+		case 2:
+			rate /= 2
+		case 0:
+			rate /= 4
+		}
+		frame := b[i:]
+
+		// look for a Xing/Info or VBRI header with the frame count
+		mono := b[i+3]>>6 == 3
+		side := 32
+		switch {
+		case version == 3 && mono:
+			side = 17
+		case version != 3 && !mono:
+			side = 17
+		case version != 3:
+			side = 9
+		}
+		if x := 4 + side; len(frame) >= x+12 && (string(frame[x:x+4]) == "Xing" || string(frame[x:x+4]) == "Info") {
+			if binary.BigEndian.Uint32(frame[x+4:])&1 != 0 {
+				frames := int64(binary.BigEndian.Uint32(frame[x+8:]))
+				m.Duration = time.Duration(frames*int64(samples)) * time.Second / time.Duration(rate)
+				return
+			}
+		}
+		if len(frame) >= 36+18 && string(frame[36:40]) == "VBRI" {
+			frames := int64(binary.BigEndian.Uint32(frame[36+14:]))
+			m.Duration = time.Duration(frames*int64(samples)) * time.Second / time.Duration(rate)
+			return
+		}
+
+		// assume constant bit rate
+		audio := end - start - int64(i)
+		if audio > 0 {
+			m.Duration = time.Duration(audio*8) * time.Second / time.Duration(bitrate)
+		}
+		return
+	}
+}
+
+// readVorbisComment parses a Vorbis comment block (used by FLAC and Ogg).
+func readVorbisComment(b []byte, m *MediaInfo) {
+	if len(b) < 8 {
+		return
+	}
+	n := int(binary.LittleEndian.Uint32(b))
+	if 4+n+4 > len(b) {
+		return
+	}
+	b = b[4+n:]
+	count := int(binary.LittleEndian.Uint32(b))
+	b = b[4:]
+	for i := 0; i < count && len(b) >= 4; i++ {
+		n = int(binary.LittleEndian.Uint32(b))
+		if n < 0 || 4+n > len(b) {
+			return
+		}
+		k, v, ok := strings.Cut(string(b[4:4+n]), "=")
+		b = b[4+n:]
+		if !ok {
+			continue
+		}
+		switch strings.ToUpper(k) {
+		case "TITLE":
+			m.Title = v
+		case "ARTIST":
+			m.Artist = v
+		case "ALBUM":
+			m.Album = v
+		case "DATE":
+			m.Year = v
+		case "GENRE":
+			m.Genre = v
+		case "TRACKNUMBER":
+			m.Track = v
+		case "COMMENT", "DESCRIPTION":
+			m.Comment = v
+		}
+	}
+}
+
+// readFLACInfo reads the stream info and Vorbis comments of a FLAC file.
+func readFLACInfo(rs io.ReadSeeker, m *MediaInfo) {
+	pos, _ := readID3v2(rs, m)
+	if b := readAt(rs, pos, 4); string(b) != "fLaC" {
+		return
+	}
+	pos += 4
+	for {
+		hdr := readAt(rs, pos, 4)
+		if len(hdr) < 4 {
+			return
+		}
+		last, kind := hdr[0]&0x80 != 0, hdr[0]&0x7f
+		n := int(hdr[1])<<16 | int(hdr[2])<<8 | int(hdr[3])
+		pos += 4
+		switch kind {
+		case 0: // STREAMINFO
+			b := readAt(rs, pos, n)
+			if len(b) >= 18 {
+				rate := int64(b[10])<<12 | int64(b[11])<<4 | int64(b[12])>>4
+				samples := int64(b[13]&0x0f)<<32 | int64(binary.BigEndian.Uint32(b[14:]))
+				if rate > 0 {
+					m.Duration = time.Duration(samples) * time.Second / time.Duration(rate)
+				}
+			}
+		case 4: // VORBIS_COMMENT
+			readVorbisComment(readAt(rs, pos, n), m)
+		}
+		pos += int64(n)
+		if last {
+			return
+		}
+	}
+}
+
+// readOggInfo reads the headers of an Ogg Vorbis or Opus file, and the
+// final granule position to compute the duration.
+func readOggInfo(rs io.ReadSeeker, m *MediaInfo) {
+	// collect the first packets of the first logical stream
+	b := readAt(rs, 0, 256*1024)
+	var (
+		packets [][]byte
+		cur     []byte
+		serial  uint32
+	)
+	for pos := 0; len(packets) < 2 && pos+27 <= len(b) && string(b[pos:pos+4]) == "OggS"; {
+		nseg := int(b[pos+26])
+		if pos+27+nseg > len(b) {
+			break
+		}
+		s := binary.LittleEndian.Uint32(b[pos+14:])
+		if pos == 0 {
+			serial = s
+		}
+		data := pos + 27 + nseg
+		for _, lace := range b[pos+27 : pos+27+nseg] {
+			if data+int(lace) > len(b) {
+				break
+			}
+			if s == serial {
+				cur = append(cur, b[data:data+int(lace)]...)
+				if lace < 255 {
+					packets = append(packets, cur)
+					cur = nil
+				}
+			}
+			data += int(lace)
+		}
+		pos = data
+	}
+	if len(packets) == 0 {
+		return
+	}
+
+	var rate, preskip int64
+	switch p := packets[0]; {
+	case len(p) >= 16 && string(p[:7]) == "\x01vorbis":
+		rate = int64(binary.LittleEndian.Uint32(p[12:]))
+		if len(packets) > 1 && strings.HasPrefix(string(packets[1]), "\x03vorbis") {
+			readVorbisComment(packets[1][7:], m)
+		}
+	case len(p) >= 12 && string(p[:8]) == "OpusHead":
+		rate = 48000 // Opus granule positions are always at 48kHz
+		preskip = int64(binary.LittleEndian.Uint16(p[10:]))
+		if len(packets) > 1 && strings.HasPrefix(string(packets[1]), "OpusTags") {
+			readVorbisComment(packets[1][8:], m)
+		}
+	default:
+		return
+	}
+
+	// find the last page of the stream
+	tail := int64(64 * 1024)
+	if tail > m.Size {
+		tail = m.Size
+	}
+	b = readAt(rs, m.Size-tail, int(tail))
+	for i := bytes.LastIndex(b, []byte("OggS")); i >= 0; i = bytes.LastIndex(b[:i], []byte("OggS")) {
+		if i+27 <= len(b) && binary.LittleEndian.Uint32(b[i+14:]) == serial {
+			granule := int64(binary.LittleEndian.Uint64(b[i+6:]))
+			if granule > preskip && rate > 0 {
+				m.Duration = time.Duration(granule-preskip) * time.Second / time.Duration(rate)
+			}
+			return
+		}
+	}
+}
+
+// readMP4Info reads the duration and iTunes-style tags of an MP4 file.
+func readMP4Info(rs io.ReadSeeker, m *MediaInfo) {
+	// locate the moov box among the top-level boxes
+	var pos int64
+	for pos < m.Size {
+		hdr := readAt(rs, pos, 16)
+		if len(hdr) < 8 {
+			return
+		}
+		n := int64(binary.BigEndian.Uint32(hdr))
+		hl := int64(8)
+		switch n {
+		case 0:
+			n = m.Size - pos
+		case 1:
+			if len(hdr) < 16 {
+				return
+			}
+			n, hl = int64(binary.BigEndian.Uint64(hdr[8:])), 16
+		}
+		if n < hl {
+			return
+		}
+		if string(hdr[4:8]) == "moov" {
+			if n-hl > maxTagSize {
+				return
+			}
+			readMoov(readAt(rs, pos+hl, int(n-hl)), m)
+			return
+		}
+		pos += n
+	}
+}
+
+// mp4Boxes calls fn for each box in b.
+func mp4Boxes(b []byte, fn func(kind string, body []byte)) {
+	for len(b) >= 8 {
+		n := int(binary.BigEndian.Uint32(b))
+		if n == 0 {
+			n = len(b)
+		}
+		if n < 8 || n > len(b) {
+			return
+		}
+		fn(string(b[4:8]), b[8:n])
+		b = b[n:]
+	}
+}
+
+// readMoov parses the contents of an MP4 moov box.
+func readMoov(b []byte, m *MediaInfo) {
+	mp4Boxes(b, func(kind string, body []byte) {
+		switch kind {
+		case "mvhd":
+			if len(body) >= 32 && body[0] == 1 {
+				scale := int64(binary.BigEndian.Uint32(body[20:]))
+				d := int64(binary.BigEndian.Uint64(body[24:]))
+				if scale > 0 {
+					m.Duration = time.Duration(d) * time.Second / time.Duration(scale)
+				}
+			} else if len(body) >= 20 {
+				scale := int64(binary.BigEndian.Uint32(body[12:]))
+				d := int64(binary.BigEndian.Uint32(body[16:]))
+				if scale > 0 {
+					m.Duration = time.Duration(d) * time.Second / time.Duration(scale)
+				}
+			}
+		case "trak":
+			mp4Boxes(body, func(kind string, body []byte) {
+				if kind != "tkhd" || m.Width != 0 {
+					return
+				}
+				// width and height are 16.16 fixed point at the end of the box
+				off := 76
+				if len(body) > 0 && body[0] == 1 {
+					off = 88
+				}
+				if len(body) >= off+8 {
+					m.Width = int(binary.BigEndian.Uint32(body[off:]) >> 16)
+					m.Height = int(binary.BigEndian.Uint32(body[off+4:]) >> 16)
+				}
+			})
+		case "udta":
+			mp4Boxes(body, func(kind string, body []byte) {
+				if kind == "meta" && len(body) > 4 {
+					mp4Boxes(body[4:], func(kind string, body []byte) {
+						if kind == "ilst" {
+							readIlst(body, m)
+						}
+					})
+				}
+			})
+		}
+	})
+}
+
+// readIlst parses iTunes metadata items.
+func readIlst(b []byte, m *MediaInfo) {
+	mp4Boxes(b, func(kind string, body []byte) {
+		var value []byte
+		mp4Boxes(body, func(k string, d []byte) {
+			if k == "data" && len(d) >= 8 {
+				value = d[8:]
+			}
+		})
+		switch kind {
+		case "\xa9nam":
+			m.Title = string(value)
+		case "\xa9ART":
+			m.Artist = string(value)
+		case "\xa9alb":
+			m.Album = string(value)
+		case "\xa9day":
+			m.Year = string(value)
+		case "\xa9gen":
+			m.Genre = string(value)
+		case "\xa9cmt":
+			m.Comment = string(value)
+		case "trkn":
+			if len(value) >= 4 {
+				m.Track = fmt.Sprint(binary.BigEndian.Uint16(value[2:]))
+			}
+		}
+	})
+}
+
+// EBML element IDs used by WebM.
+const (
+	ebmlSegment       = 0x18538067
+	ebmlInfo          = 0x1549a966
+	ebmlTimecodeScale = 0x2ad7b1
+	ebmlDuration      = 0x4489
+	ebmlTitle         = 0x7ba9
+	ebmlTracks        = 0x1654ae6b
+	ebmlTrackEntry    = 0xae
+	ebmlVideo         = 0xe0
+	ebmlPixelWidth    = 0xb0
+	ebmlPixelHeight   = 0xba
+)
+
+// ebmlVint reads a variable length EBML integer, returning the value, its
+// length, and whether all value bits were set (meaning "unknown size").
+// The length marker is kept for IDs and removed for sizes.
+func ebmlVint(b []byte, keepMarker bool) (uint64, int, bool) {
+	if len(b) == 0 || b[0] == 0 {
+		return 0, 0, false
+	}
+	n := 1
+	for mask := byte(0x80); b[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if n > len(b) {
+		return 0, 0, false
+	}
+	v := uint64(b[0])
+	if !keepMarker {
+		v &= uint64(0xff >> n)
+	}
+	all := v == uint64(0xff>>n)
+	for _, c := range b[1:n] {
+		v = v<<8 | uint64(c)
+		all = all && c == 0xff
+	}
+	return v, n, all
+}
+
+// ebmlElements calls fn for each element in b. Elements of unknown size
+// extend to the end of b.
+func ebmlElements(b []byte, fn func(id uint64, body []byte)) {
+	for len(b) > 0 {
+		id, n, _ := ebmlVint(b, true)
+		if n == 0 {
+			return
+		}
+		size, m, unknown := ebmlVint(b[n:], false)
+		if m == 0 {
+			return
+		}
+		b = b[n+m:]
+		if unknown || size > uint64(len(b)) {
+			size = uint64(len(b))
+		}
+		fn(id, b[:size])
+		b = b[size:]
+	}
+}
+
+// ebmlUint decodes an EBML unsigned integer.
+func ebmlUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// readWebMInfo reads the duration, title, and video size of a WebM file.
+// These are found in the headers at the start of the file.
+func readWebMInfo(rs io.ReadSeeker, m *MediaInfo) {
+	b := readAt(rs, 0, 1024*1024)
+	ebmlElements(b, func(id uint64, body []byte) {
+		if id != ebmlSegment {
+			return
+		}
+		ebmlElements(body, func(id uint64, body []byte) {
+			switch id {
+			case ebmlInfo:
+				scale := uint64(1000000)
+				var d float64
+				ebmlElements(body, func(id uint64, body []byte) {
+					switch id {
+					case ebmlTimecodeScale:
+						scale = ebmlUint(body)
+					case ebmlDuration:
+						switch len(body) {
+						case 4:
+							d = float64(math.Float32frombits(binary.BigEndian.Uint32(body)))
+						case 8:
+							d = math.Float64frombits(binary.BigEndian.Uint64(body))
+						}
+					case ebmlTitle:
+						m.Title = string(body)
+					}
+				})
+				m.Duration = time.Duration(d * float64(scale))
+			case ebmlTracks:
+				ebmlElements(body, func(id uint64, body []byte) {
+					if id != ebmlTrackEntry || m.Width != 0 {
+						return
+					}
+					ebmlElements(body, func(id uint64, body []byte) {
+						if id != ebmlVideo {
+							return
+						}
+						ebmlElements(body, func(id uint64, body []byte) {
+							switch id {
+							case ebmlPixelWidth:
+								m.Width = int(ebmlUint(body))
+							case ebmlPixelHeight:
+								m.Height = int(ebmlUint(body))
+							}
+						})
+					})
+				})
+			}
+		})
+	})
+}
+
+// posterExtensions lists the image types that can serve as a poster or cover.
+var posterExtensions = []string{".jpg", ".jpeg", ".png", ".webp", ".gif"}
+
+// findPoster looks for an image with the same base name as the media file.
+func (vfs *FS) findPoster(pathname string) string {
+	base := strings.TrimSuffix(pathname, path.Ext(pathname))
+	for _, ext := range posterExtensions {
+		if fi, err := fs.Stat(vfs.fs, base+ext); err == nil && fi.Mode().IsRegular() {
+			return path.Base(base + ext)
+		}
+	}
+	return ""
+}
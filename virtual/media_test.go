@@ -0,0 +1,134 @@
+package virtual
+
+import (
+	"encoding/xml"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestReadMediaInfo(t *testing.T) {
+	f, err := os.Open("../example/podcasts/tone.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m := readMediaInfo(f)
+	if m.MimeType != "audio/mpeg" {
+		t.Errorf("Expected audio/mpeg but got %q", m.MimeType)
+	}
+	if m.Title != "Whisper Test Tone" || m.Artist != "ancientlore" || m.Album != "Whisper Example" || m.Track != "1" {
+		t.Errorf("Unexpected tags: %#v", m)
+	}
+	if m.Duration.Round(100*time.Millisecond) != 2*time.Second {
+		t.Errorf("Expected 2s duration but got %v", m.Duration)
+	}
+	if m.Clock() != "0:02" {
+		t.Errorf("Expected 0:02 but got %q", m.Clock())
+	}
+}
+
+func TestMediaClock(t *testing.T) {
+	tests := map[time.Duration]string{
+		0:                "0:00",
+		59 * time.Second: "0:59",
+		61 * time.Second: "1:01",
+		time.Hour + 2*time.Minute + 3*time.Second: "1:02:03",
+	}
+	for d, expect := range tests {
+		if s := (MediaInfo{Duration: d}).Clock(); s != expect {
+			t.Errorf("Expected %q for %v but got %q", expect, d, s)
+		}
+	}
+}
+
+func TestPodcastFeed(t *testing.T) {
+	fileSys, err := New(os.DirFS("../example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(fileSys, "podcasts/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed rss
+	err = xml.Unmarshal(b, &feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Channel.Title != "Podcasts" {
+		t.Errorf("Expected channel title from index.md but got %q", feed.Channel.Title)
+	}
+	if len(feed.Channel.Items) != 1 {
+		t.Fatalf("Expected one item but got %d", len(feed.Channel.Items))
+	}
+	item := feed.Channel.Items[0]
+	if item.Enclosure.Type != "audio/mpeg" || item.Enclosure.Length == 0 || !strings.HasSuffix(item.Enclosure.URL, "/podcasts/tone.mp3") {
+		t.Errorf("Unexpected enclosure: %#v", item.Enclosure)
+	}
+	if !strings.Contains(string(b), "<itunes:duration>0:02</itunes:duration>") {
+		t.Errorf("Expected item duration in %s", b)
+	}
+
+	_, err = fs.ReadFile(fileSys, "photos/feed.xml")
+	if err == nil {
+		t.Errorf("Expected no feed for a folder without audio")
+	}
+}
+
+func TestPodcastFeedURLs(t *testing.T) {
+	fileSys, err := New(fstest.MapFS{
+		"whisper.cfg":             {Data: []byte("baseurl = \"https://example.com/\"\n")},
+		"podcasts/my show #1.mp3": {Data: []byte("mp3")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(fileSys, "podcasts/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed rss
+	err = xml.Unmarshal(b, &feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Channel.Items) != 1 {
+		t.Fatalf("Expected one item but got %d", len(feed.Channel.Items))
+	}
+	item := feed.Channel.Items[0]
+	if item.Link != "https://example.com/podcasts/my%20show%20%231.html" {
+		t.Errorf("Unexpected link %q", item.Link)
+	}
+	if item.GUID != "https://example.com/podcasts/my%20show%20%231.mp3" || item.Enclosure.URL != item.GUID {
+		t.Errorf("Unexpected guid %q or enclosure %q", item.GUID, item.Enclosure.URL)
+	}
+}
+
+func TestReadWebMInfo(t *testing.T) {
+	// EBML header, then a segment of unknown size with info and tracks
+	b := []byte{
+		0x1a, 0x45, 0xdf, 0xa3, 0x80, // EBML header, empty
+		0x18, 0x53, 0x80, 0x67, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // Segment, unknown size
+		0x15, 0x49, 0xa9, 0x66, 0x8e, // Info
+		0x2a, 0xd7, 0xb1, 0x83, 0x0f, 0x42, 0x40, // TimecodeScale 1000000
+		0x44, 0x89, 0x84, 0x45, 0x9c, 0x40, 0x00, // Duration 5000.0 (float32)
+		0x16, 0x54, 0xae, 0x6b, 0x8a, // Tracks
+		0xae, 0x88, // TrackEntry
+		0xe0, 0x86, // Video
+		0xb0, 0x81, 0x40, // PixelWidth 64
+		0xba, 0x81, 0x30, // PixelHeight 48
+	}
+	var m MediaInfo
+	readWebMInfo(strings.NewReader(string(b)), &m)
+	if m.Duration != 5*time.Second {
+		t.Errorf("Expected 5s but got %v", m.Duration)
+	}
+	if m.Width != 64 || m.Height != 48 {
+		t.Errorf("Expected 64x48 but got %dx%d", m.Width, m.Height)
+	}
+}
@@ -0,0 +1,96 @@
+package virtual
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+)
+
+// latest returns the latest of the given times.
+func latest(times ...time.Time) time.Time {
+	var t time.Time
+	for _, tm := range times {
+		if tm.After(t) {
+			t = tm
+		}
+	}
+	return t
+}
+
+// loadModTime returns the latest modification time of the templates, the
+// configuration, and the data files, which affect every rendered page.
+func (vfs *FS) loadModTime() time.Time {
+	var t time.Time
+	if fi, err := fs.Stat(vfs.fs, "whisper.cfg"); err == nil {
+		t = fi.ModTime()
+	}
+	for _, folder := range []string{"template", dataFolder} {
+		err := fs.WalkDir(vfs.fs, folder, func(pathname string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if fi, err := d.Info(); err == nil {
+				t = latest(t, fi.ModTime())
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("loadModTime cannot read folder", "folder", folder, "error", err)
+		}
+	}
+	return t
+}
+
+// templateModTime returns the modification time found when the templates were loaded.
+func (vfs *FS) templateModTime() time.Time {
+	vfs.tplMutex.RLock()
+	defer vfs.tplMutex.RUnlock()
+	return vfs.tplModTime
+}
+
+// folderModTime returns the latest modification time of what a page rendered from
+// a source file in the given folder depends on: the templates, configuration, and
+// data files, the front matter defaults that apply to the folder, and the folder and
+// its entries, which the page may list.
+func (vfs *FS) folderModTime(folder string) time.Time {
+	t := vfs.templateModTime()
+	folder = strings.Trim(path.Clean("/"+folder), "/")
+	if folder == "" {
+		folder = "."
+	}
+	if fi, err := fs.Stat(vfs.fs, folder); err == nil {
+		t = latest(t, fi.ModTime())
+	}
+	entries, _ := fs.ReadDir(vfs.fs, folder)
+	for _, entry := range entries {
+		if fi, err := entry.Info(); err == nil {
+			t = latest(t, fi.ModTime())
+		}
+	}
+	// defaults in the folder itself are one of its entries
+	for f := folder; f != "."; {
+		f = path.Dir(f)
+		if fi, err := fs.Stat(vfs.fs, path.Join(f, defaultsFile)); err == nil {
+			t = latest(t, fi.ModTime())
+		}
+	}
+	return t
+}
+
+// modTime returns the modification time of a page rendered from a source file in
+// the given folder and modified at t, which is the latest of t and the folder's
+// dependencies. Unlike time.Now, it stays the same until something the page depends
+// on changes, so that If-Modified-Since requests can be answered with 304 Not Modified.
+func (vfs *FS) modTime(folder string, t time.Time) time.Time {
+	return latest(t, vfs.folderModTime(folder))
+}
+
+// siteModTime returns the latest modification time of the site's content and of the
+// templates, configuration, and data files, for pages like the site map that list
+// the whole site.
+func (vfs *FS) siteModTime() time.Time {
+	return latest(vfs.templateModTime(), vfs.contentModTime())
+}
@@ -0,0 +1,41 @@
+package virtual
+
+import "time"
+
+// Observer is notified when pages are rendered and templates are reloaded, which
+// is useful for collecting metrics.
+type Observer interface {
+	// Rendered is called after rendering a page of the given kind, which is one of
+	// "markdown", "image", "video", "audio", "sitemap", or "feed".
+	Rendered(kind string, d time.Duration)
+	// TemplatesLoaded is called after the templates are reloaded, with the error if it failed.
+	TemplatesLoaded(err error)
+}
+
+// Observe sets the Observer to notify of rendering and template reloading.
+func (vfs *FS) Observe(o Observer) {
+	vfs.tplMutex.Lock()
+	defer vfs.tplMutex.Unlock()
+	vfs.observer = o
+}
+
+// rendered notifies the Observer, if any, that a page of the given kind was
+// rendered starting at the given time. It is meant to be deferred.
+func (vfs *FS) rendered(kind string, start time.Time) {
+	vfs.tplMutex.RLock()
+	o := vfs.observer
+	vfs.tplMutex.RUnlock()
+	if o != nil {
+		o.Rendered(kind, time.Since(start))
+	}
+}
+
+// templatesLoaded notifies the Observer, if any, that the templates were reloaded.
+func (vfs *FS) templatesLoaded(err error) {
+	vfs.tplMutex.RLock()
+	o := vfs.observer
+	vfs.tplMutex.RUnlock()
+	if o != nil {
+		o.TemplatesLoaded(err)
+	}
+}
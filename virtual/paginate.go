@@ -0,0 +1,85 @@
+package virtual
+
+import (
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Pager holds one page of a paginated list of files.
+type Pager struct {
+	Items      []File // files on the current page
+	Number     int    // current page number, starting at 1
+	Size       int    // maximum number of files per page
+	TotalPages int    // number of pages
+	TotalItems int    // number of files in all pages
+	First      string // URL of the first page
+	Last       string // URL of the last page
+	Prev       string // URL of the previous page, or empty if there is none
+	Next       string // URL of the next page, or empty if there is none
+}
+
+// paginate splits the files into pages of the given size and returns the
+// current page, and is used in templates. It notes the number of pages, so
+// that pages past the end are not served.
+func paginate(page PageInfo, f []File, size int) *Pager {
+	if size <= 0 {
+		size = len(f)
+	}
+	p := Pager{
+		Number:     max(page.Number, 1),
+		Size:       size,
+		TotalItems: len(f),
+		TotalPages: 1,
+	}
+	if size > 0 {
+		p.TotalPages = max((len(f)+size-1)/size, 1)
+	}
+	if start := (p.Number - 1) * size; start < len(f) {
+		p.Items = f[start:min(start+size, len(f))]
+	}
+	if page.pages != nil {
+		*page.pages = max(*page.pages, p.TotalPages)
+	}
+	p.First = page.pageURL(1)
+	p.Last = page.pageURL(p.TotalPages)
+	if p.Number > 1 {
+		p.Prev = page.pageURL(min(p.Number-1, p.TotalPages))
+	}
+	if p.Number < p.TotalPages {
+		p.Next = page.pageURL(p.Number + 1)
+	}
+	return &p
+}
+
+// pageURL returns the URL of the given page of a paginated listing.
+// The first page is the listing itself, and later pages are found in
+// a "page" folder named after the listing.
+func (p PageInfo) pageURL(number int) string {
+	base := p.Path
+	if p.Filename != "index.html" {
+		if number <= 1 {
+			return path.Join(p.Path, p.Filename)
+		}
+		base = path.Join(p.Path, strings.TrimSuffix(p.Filename, path.Ext(p.Filename)))
+	} else if number <= 1 {
+		return p.Path
+	}
+	return path.Join(base, "page", strconv.Itoa(number)+".html")
+}
+
+// splitPageName checks if name is a page of a paginated listing like
+// "articles/page/2.html", returning the listing's base path ("articles")
+// and the page number.
+func splitPageName(name string) (string, int, bool) {
+	dir, file := path.Split(name)
+	if path.Base(dir) != "page" || path.Ext(file) != ".html" {
+		return "", 0, false
+	}
+	s := strings.TrimSuffix(file, ".html")
+	number, err := strconv.Atoi(s)
+	if err != nil || number < 2 || strconv.Itoa(number) != s {
+		return "", 0, false
+	}
+	return path.Dir(path.Dir(dir)), number, true
+}
@@ -0,0 +1,173 @@
+package virtual
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rss is an iTunes-compatible podcast feed.
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	ITunes  string     `xml:"xmlns:itunes,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Summary     string    `xml:"itunes:summary,omitempty"`
+	Author      string    `xml:"itunes:author,omitempty"`
+	Explicit    string    `xml:"itunes:explicit"`
+	PubDate     string    `xml:"pubDate,omitempty"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title     string       `xml:"title"`
+	Link      string       `xml:"link"`
+	GUID      string       `xml:"guid"`
+	PubDate   string       `xml:"pubDate"`
+	Author    string       `xml:"itunes:author,omitempty"`
+	Summary   string       `xml:"itunes:summary,omitempty"`
+	Duration  string       `xml:"itunes:duration,omitempty"`
+	Episode   string       `xml:"itunes:episode,omitempty"`
+	Enclosure rssEnclosure `xml:"enclosure"`
+	date      time.Time    // used for sorting
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// newPodcastFile generates an RSS feed for the audio files in the given folder,
+// returning the resulting virtualFile. The channel title and description come
+// from the folder's index.md front matter.
+func (vfs *FS) newPodcastFile(folder, pathname string) (fs.File, error) {
+	defer vfs.rendered("feed", time.Now())
+
+	entries, err := fs.ReadDir(vfs.fs, folder)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: pathname, Err: fs.ErrNotExist}
+	}
+
+	baseURL := strings.TrimSuffix(vfs.config().BaseURL, "/")
+	if baseURL == "" {
+		slog.Warn("Podcast feed has relative URLs; set baseurl in whisper.cfg", "path", pathname)
+	}
+	folderURL := baseURL + feedPath(folder) + "/"
+
+	var front FrontMatter
+	front.Title = path.Base(folder)
+	err = vfs.readFrontMatter(path.Join(folder, "index.md"), &front)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("Podcast feed problem reading front matter", "error", err)
+	}
+
+	var (
+		items   []rssItem
+		modTime time.Time
+	)
+	for _, entry := range entries {
+		nm := entry.Name()
+		if containsSpecialFile(nm) || !hasAudioExtension(nm) {
+			continue
+		}
+		f, err := vfs.fs.Open(path.Join(folder, nm))
+		if err != nil {
+			continue
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			continue
+		}
+		media := readMediaInfo(f)
+		f.Close()
+
+		title := media.Title
+		if title == "" {
+			title = strings.TrimSuffix(nm, path.Ext(nm))
+		}
+		item := rssItem{
+			Title:   title,
+			Link:    baseURL + feedPath(folder, strings.TrimSuffix(nm, path.Ext(nm))+".html"),
+			GUID:    baseURL + feedPath(folder, nm),
+			PubDate: fi.ModTime().Format(time.RFC1123Z),
+			Author:  media.Artist,
+			Summary: media.Comment,
+			Episode: media.Track,
+			Enclosure: rssEnclosure{
+				URL:    baseURL + feedPath(folder, nm),
+				Length: fi.Size(),
+				Type:   media.MimeType,
+			},
+		}
+		if media.Duration > 0 {
+			item.Duration = media.Clock()
+		}
+		item.date = fi.ModTime()
+		items = append(items, item)
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	if len(items) == 0 {
+		return nil, &fs.PathError{Op: "open", Path: pathname, Err: fs.ErrNotExist}
+	}
+
+	// newest episodes first
+	sort.SliceStable(items, func(i, j int) bool { return items[j].date.Before(items[i].date) })
+	feed := rss{
+		Version: "2.0",
+		ITunes:  "http://www.itunes.com/dtds/podcast-1.0.dtd",
+		Channel: rssChannel{
+			Title:       front.Title,
+			Link:        folderURL,
+			Description: front.Description,
+			Summary:     front.Description,
+			Author:      items[0].Author,
+			Explicit:    "false",
+			PubDate:     modTime.Format(time.RFC1123Z),
+			Items:       items,
+		},
+	}
+
+	var wtr bytes.Buffer
+	wtr.WriteString(xml.Header)
+	enc := xml.NewEncoder(&wtr)
+	enc.Indent("", "  ")
+	err = enc.Encode(feed)
+	if err != nil {
+		return nil, fmt.Errorf("newPodcastFile: %w", err)
+	}
+
+	return &virtualFile{
+		fi: fileInfo{
+			nm: path.Base(pathname),
+			sz: int64(wtr.Len()),
+			md: 0444,
+			mt: vfs.modTime(folder, modTime),
+		},
+		reader: bytes.NewReader(wtr.Bytes()),
+	}, nil
+}
+
+// feedPath joins the elements into an escaped path for the URLs of a feed, so
+// that names with spaces or characters like "#" work.
+func feedPath(elem ...string) string {
+	u := url.URL{Path: "/" + path.Join(elem...)}
+	return u.EscapedPath()
+}
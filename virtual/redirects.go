@@ -0,0 +1,80 @@
+package virtual
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// redirectsFile is the hidden file at the root holding Netlify-style redirect rules.
+const redirectsFile = "_redirects"
+
+// loadRedirects reads the rules of the _redirects file, if there is one.
+func (vfs *FS) loadRedirects() ([]Redirect, error) {
+	b, err := fs.ReadFile(vfs.fs, redirectsFile)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot read redirects file: %w", err)
+	}
+	redirects, err := parseRedirects(b)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse redirects file: %w", err)
+	}
+	return redirects, nil
+}
+
+// parseRedirects decodes rules in the format of Netlify's _redirects file, one per
+// line, like "/news/* /blog/:splat 302". Blank lines and lines starting with "#"
+// are skipped. The status is optional, and a "!" after it is accepted but has no
+// effect, because rules always apply even when a file exists at the path.
+func parseRedirects(b []byte) ([]Redirect, error) {
+	var redirects []Redirect
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("line %d: expected a path, a target, and an optional status", n)
+		}
+		r := Redirect{From: fields[0], To: fields[1]}
+		if len(fields) == 3 {
+			status, err := strconv.Atoi(strings.TrimSuffix(fields[2], "!"))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad status %q", n, fields[2])
+			}
+			r.Status = status
+		}
+		redirects = append(redirects, r)
+	}
+	return redirects, scanner.Err()
+}
+
+// validate checks that the rule has a path to match, a target, and a supported status.
+func (r Redirect) validate() error {
+	if !strings.HasPrefix(r.From, "/") {
+		return fmt.Errorf("redirect from %q must start with \"/\"", r.From)
+	}
+	if r.To == "" {
+		return fmt.Errorf("redirect from %q has no target", r.From)
+	}
+	switch r.Status {
+	case 0, http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	case http.StatusOK:
+		if !strings.HasPrefix(r.To, "/") {
+			return fmt.Errorf("rewrite from %q must be to a path on the site", r.From)
+		}
+	default:
+		return fmt.Errorf("redirect from %q has unsupported status %d", r.From, r.Status)
+	}
+	return nil
+}
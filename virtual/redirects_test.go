@@ -0,0 +1,51 @@
+package virtual
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRedirects(t *testing.T) {
+	fileSys, err := New(fstest.MapFS{
+		"whisper.cfg":           {Data: []byte("[[redirects]]\nfrom = \"/old.html\"\nto = \"/new.html\"\n")},
+		"_redirects":            {Data: []byte("# moved\n/news/*  /blog/:splat  302\n\n/app/*  /app/index.html  200!\n")},
+		"template/default.html": {Data: []byte(`{{define "default"}}{{.Content}}{{end}}`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := fileSys.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Redirect{
+		{From: "/old.html", To: "/new.html"},
+		{From: "/news/*", To: "/blog/:splat", Status: 302},
+		{From: "/app/*", To: "/app/index.html", Status: 200},
+	}
+	if len(cfg.Redirects) != len(expect) {
+		t.Fatalf("Expected %d redirects but got %+v", len(expect), cfg.Redirects)
+	}
+	for i := range expect {
+		if cfg.Redirects[i] != expect[i] {
+			t.Errorf("Expected %+v but got %+v", expect[i], cfg.Redirects[i])
+		}
+	}
+	if err = cfg.Validate(); err != nil {
+		t.Errorf("Expected valid redirects: %v", err)
+	}
+	if _, err = fs.Stat(fileSys, "_redirects"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected _redirects to be hidden: %v", err)
+	}
+	if _, err = parseRedirects([]byte("/a /b 301 extra\n")); err == nil {
+		t.Errorf("Expected error for extra fields")
+	}
+	bad := []Redirect{{From: "old", To: "/new"}, {From: "/old"}, {From: "/old", To: "/new", Status: 404}, {From: "/old", To: "https://example.com/", Status: 200}}
+	for _, r := range bad {
+		if err = (&Config{Redirects: []Redirect{r}}).Validate(); err == nil {
+			t.Errorf("Expected invalid redirect %+v", r)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package virtual
+
+import (
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+)
+
+// pages returns the Markdown pages of the whole site with their front matter.
+// Error pages and hidden or special files are skipped. The pages are found once
+// and kept until the content or templates change.
+func (vfs *FS) pages() []File {
+	key := vfs.indexKey()
+	idx := &vfs.index
+	idx.mutex.Lock()
+	idx.update(key)
+	if idx.paged {
+		f := idx.pages
+		idx.mutex.Unlock()
+		return f
+	}
+	idx.mutex.Unlock()
+	f := vfs.findPages()
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if idx.key == key {
+		idx.pages, idx.paged = f, true
+	}
+	return f
+}
+
+// findPages walks the site to find the Markdown pages.
+func (vfs *FS) findPages() []File {
+	var f []File
+	err := fs.WalkDir(vfs.fs, ".", func(pathname string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if pathname != "." && (isHiddenFile(pathname) || containsSpecialFile(pathname)) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || path.Ext(pathname) != ".md" || pathname == "404.md" || pathname == "500.md" || vfs.variantLanguage(pathname) != "" {
+			return nil
+		}
+		folder, nm := path.Split(pathname)
+		fm := FrontMatter{
+			Title: strings.TrimSuffix(nm, ".md"),
+		}
+		if fi, err := d.Info(); err == nil {
+			fm.Date = fi.ModTime().Local()
+		}
+		err = vfs.readFrontMatter(pathname, &fm)
+		if err != nil {
+			slog.Warn("pages problem reading front matter", "error", err)
+		}
+		f = append(f, File{FrontMatter: fm, Filename: strings.TrimSuffix(nm, ".md") + ".html", Path: folderURL(folder)})
+		return nil
+	})
+	if err != nil {
+		slog.Error("pages: WalkDir failed", "error", err)
+	}
+	return f
+}
+
+// series returns the pages of the named series sorted by weight in reverse
+// order, like the other sort functions, and is used in templates.
+func (vfs *FS) series(name string) []File {
+	var f []File
+	if name == "" {
+		return f
+	}
+	for _, p := range vfs.pages() {
+		if p.FrontMatter.Series == name {
+			f = append(f, p)
+		}
+	}
+	return sortByWeight(f)
+}
+
+// related returns up to limit pages sharing tags with the page at pathname,
+// with the most shared tags first, and is used in templates.
+func (vfs *FS) related(pathname string, limit int) []File {
+	all := vfs.pages()
+	var tags []string
+	for _, p := range all {
+		if p.is(pathname) {
+			tags = p.FrontMatter.Tags
+			break
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	type scored struct {
+		File
+		score int
+	}
+	var r []scored
+	for _, p := range all {
+		if p.is(pathname) {
+			continue
+		}
+		score := 0
+		for _, t := range p.FrontMatter.Tags {
+			for _, u := range tags {
+				if strings.EqualFold(t, u) {
+					score++
+					break
+				}
+			}
+		}
+		if score > 0 {
+			r = append(r, scored{File: p, score: score})
+		}
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		if r[i].score != r[j].score {
+			return r[i].score > r[j].score
+		}
+		return r[j].FrontMatter.Date.Before(r[i].FrontMatter.Date)
+	})
+	if limit > 0 && len(r) > limit {
+		r = r[:limit]
+	}
+	f := make([]File, len(r))
+	for i := range r {
+		f[i] = r[i].File
+	}
+	return f
+}
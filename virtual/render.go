@@ -9,17 +9,26 @@ import (
 	"log/slog"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pelletier/go-toml/v2"
-	"github.com/russross/blackfriday/v2"
 )
 
 // newMarkdownFile reads the underlying markdown file, extracts the front matter,
 // renders the markdown, and executes the specified template, returning the
 // resulting virtualFile.
 func (vfs *FS) newMarkdownFile(f fs.File, pathname string) (fs.File, error) {
+	return vfs.newMarkdownPage(f, pathname, 1)
+}
+
+// newMarkdownPage is like newMarkdownFile, but renders the given page number
+// for templates that paginate. Pages after the first are named like "2.html",
+// because they are served from a "page" folder.
+func (vfs *FS) newMarkdownPage(f fs.File, pathname string, number int) (fs.File, error) {
+	defer vfs.rendered("markdown", time.Now())
+
 	fi, err := f.Stat()
 	if err != nil {
 		return nil, fmt.Errorf("newMarkdownFile: %w", err)
@@ -37,6 +46,10 @@ func (vfs *FS) newMarkdownFile(f fs.File, pathname string) (fs.File, error) {
 	front.Template = "default"
 	front.Title = strings.TrimSuffix(fi.Name(), path.Ext(fi.Name()))
 	front.OriginalFile = fi.Name()
+	err = vfs.readDefaults(path.Dir(vfs.markdownFor(pathname)), &front)
+	if err != nil {
+		return nil, fmt.Errorf("newMarkdownFile: %w", err)
+	}
 	if len(fm) > 0 {
 		err = toml.Unmarshal(fm, &front)
 		if err != nil {
@@ -44,17 +57,25 @@ func (vfs *FS) newMarkdownFile(f fs.File, pathname string) (fs.File, error) {
 		}
 	}
 
-	md := template.HTML(blackfriday.Run(r, blackfriday.WithExtensions(blackfriday.CommonExtensions|blackfriday.Footnotes)))
-
 	// prepare template data
 	p, bn := path.Split(pathname)
+	var pages int
 	var data = data{
 		FrontMatter: front,
 		Page: PageInfo{
-			Path:     "/" + p,
-			Filename: bn,
+			Path:        "/" + p,
+			Filename:    bn,
+			Number:      number,
+			Breadcrumbs: vfs.breadcrumbs(pathname),
+			pages:       &pages,
 		},
-		Content: md,
+		Site:         vfs.site(),
+		Language:     vfs.pageLanguage(pathname),
+		Translations: vfs.translations(pathname),
+	}
+	data.Content = vfs.renderContent(r, data)
+	if number > 1 {
+		bn = strconv.Itoa(number) + ".html"
 	}
 
 	// Render the HTML template
@@ -64,17 +85,21 @@ func (vfs *FS) newMarkdownFile(f fs.File, pathname string) (fs.File, error) {
 	}
 	tpl := vfs.getTemplates()
 	var wtr bytes.Buffer
-	err = tpl.ExecuteTemplate(&wtr, templateName, data)
+	err = tpl.ExecuteTemplate(&wtr, lookupTemplate(tpl, p, templateName), data)
 	if err != nil {
 		slog.Warn("Error executing markdown template", "error", err)
 	}
+	// only listings that paginate have later pages, and only as many as they need
+	if number > pages && number > 1 {
+		return nil, &fs.PathError{Op: "open", Path: pathname, Err: fs.ErrNotExist}
+	}
 
 	return &virtualFile{
 		fi: fileInfo{
 			nm: bn,
 			sz: int64(wtr.Len()),
 			md: fi.Mode(),
-			mt: time.Now(), // needs to be more dynamic than fi.ModTime(),
+			mt: vfs.modTime(path.Dir(vfs.markdownFor(pathname)), fi.ModTime()),
 		},
 		reader: bytes.NewReader(wtr.Bytes()),
 	}, nil
@@ -84,6 +109,8 @@ func (vfs *FS) newMarkdownFile(f fs.File, pathname string) (fs.File, error) {
 // and executes the specified template, returning the resulting
 // virtualFile.
 func (vfs *FS) newImageFile(f fs.File, pathname string) (fs.File, error) {
+	defer vfs.rendered("image", time.Now())
+
 	fi, err := f.Stat()
 	if err != nil {
 		return nil, err
@@ -99,15 +126,17 @@ func (vfs *FS) newImageFile(f fs.File, pathname string) (fs.File, error) {
 			OriginalFile: fi.Name(), // allows reference to image in template
 		},
 		Page: PageInfo{
-			Path:     "/" + p,
-			Filename: bn,
+			Path:        "/" + p,
+			Filename:    bn,
+			Breadcrumbs: vfs.breadcrumbs(pathname),
 		},
+		Site: vfs.site(),
 	}
 
 	// Render the HTML template
 	tpl := vfs.getTemplates()
 	var wtr bytes.Buffer
-	err = tpl.ExecuteTemplate(&wtr, "image", data)
+	err = tpl.ExecuteTemplate(&wtr, lookupTemplate(tpl, p, "image"), data)
 	if err != nil {
 		slog.Warn("Error executing image template", "error", err)
 	}
@@ -117,7 +146,7 @@ func (vfs *FS) newImageFile(f fs.File, pathname string) (fs.File, error) {
 			nm: bn,
 			sz: int64(wtr.Len()),
 			md: fi.Mode(),
-			mt: time.Now(), // needs to be more dynamic than fi.ModTime(),
+			mt: vfs.modTime(p, fi.ModTime()),
 		},
 		reader: bytes.NewReader(wtr.Bytes()),
 	}, nil
@@ -127,11 +156,16 @@ func (vfs *FS) newImageFile(f fs.File, pathname string) (fs.File, error) {
 // and executes the specified template, returning the resulting
 // virtualFile.
 func (vfs *FS) newVideoFile(f fs.File, pathname string) (fs.File, error) {
+	defer vfs.rendered("video", time.Now())
+
 	fi, err := f.Stat()
 	if err != nil {
 		return nil, err
 	}
 
+	media := readMediaInfo(f)
+	media.Poster = vfs.findPoster(pathname)
+
 	// prepare template data
 	p, bn := path.Split(pathname)
 	var data = data{
@@ -142,15 +176,21 @@ func (vfs *FS) newVideoFile(f fs.File, pathname string) (fs.File, error) {
 			OriginalFile: fi.Name(), // allows reference to image in template
 		},
 		Page: PageInfo{
-			Path:     "/" + p,
-			Filename: bn,
+			Path:        "/" + p,
+			Filename:    bn,
+			Breadcrumbs: vfs.breadcrumbs(pathname),
 		},
+		Site:  vfs.site(),
+		Media: media,
+	}
+	if media.Title != "" {
+		data.FrontMatter.Title = media.Title
 	}
 
 	// Render the HTML template
 	tpl := vfs.getTemplates()
 	var wtr bytes.Buffer
-	err = tpl.ExecuteTemplate(&wtr, "video", data)
+	err = tpl.ExecuteTemplate(&wtr, lookupTemplate(tpl, p, "video"), data)
 	if err != nil {
 		slog.Warn("Error executing video template", "error", err)
 	}
@@ -160,7 +200,60 @@ func (vfs *FS) newVideoFile(f fs.File, pathname string) (fs.File, error) {
 			nm: bn,
 			sz: int64(wtr.Len()),
 			md: fi.Mode(),
-			mt: time.Now(), // needs to be more dynamic than fi.ModTime(),
+			mt: vfs.modTime(p, fi.ModTime()),
+		},
+		reader: bytes.NewReader(wtr.Bytes()),
+	}, nil
+}
+
+// newAudioFile reads the underlying audio file's metadata, creates front matter,
+// and executes the specified template, returning the resulting virtualFile.
+func (vfs *FS) newAudioFile(f fs.File, pathname string) (fs.File, error) {
+	defer vfs.rendered("audio", time.Now())
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	media := readMediaInfo(f)
+	media.Poster = vfs.findPoster(pathname)
+
+	// prepare template data
+	p, bn := path.Split(pathname)
+	var data = data{
+		FrontMatter: FrontMatter{
+			Title:        strings.TrimSuffix(fi.Name(), path.Ext(fi.Name())),
+			Date:         fi.ModTime().Local(),
+			Template:     "audio",
+			OriginalFile: fi.Name(), // allows reference to audio in template
+		},
+		Page: PageInfo{
+			Path:        "/" + p,
+			Filename:    bn,
+			Breadcrumbs: vfs.breadcrumbs(pathname),
+		},
+		Site:  vfs.site(),
+		Media: media,
+	}
+	if media.Title != "" {
+		data.FrontMatter.Title = media.Title
+	}
+
+	// Render the HTML template
+	tpl := vfs.getTemplates()
+	var wtr bytes.Buffer
+	err = tpl.ExecuteTemplate(&wtr, lookupTemplate(tpl, p, "audio"), data)
+	if err != nil {
+		slog.Warn("Error executing audio template", "error", err)
+	}
+
+	return &virtualFile{
+		fi: fileInfo{
+			nm: bn,
+			sz: int64(wtr.Len()),
+			md: fi.Mode(),
+			mt: vfs.modTime(p, fi.ModTime()),
 		},
 		reader: bytes.NewReader(wtr.Bytes()),
 	}, nil
@@ -170,17 +263,26 @@ func (vfs *FS) newVideoFile(f fs.File, pathname string) (fs.File, error) {
 // directory listing, and executes the template, returning the resulting
 // virtualFile.
 func (vfs *FS) newSitemapFile(f fs.File, pathname string) (fs.File, error) {
+	defer vfs.rendered("sitemap", time.Now())
+
 	fi, err := f.Stat()
 	if err != nil {
 		return nil, err
 	}
 
-	sitemapTpl, err := template.New("sitemap").ParseFS(vfs.fs, pathname)
+	funcMap := template.FuncMap{
+		"join":         path.Join,
+		"translations": vfs.translations,
+		"absurl":       vfs.absurl,
+		"site":         vfs.site,
+	}
+	sitemapTpl, err := template.New("sitemap").Funcs(funcMap).ParseFS(vfs.fs, pathname)
 	if err != nil {
 		return nil, err
 	}
 
 	var files []string
+	modTime := latest(fi.ModTime(), vfs.siteModTime())
 	err = fs.WalkDir(vfs, ".", func(path string, d fs.DirEntry, err error) error {
 		if err == nil && path != "" {
 			if path == "." {
@@ -213,12 +315,31 @@ func (vfs *FS) newSitemapFile(f fs.File, pathname string) (fs.File, error) {
 			nm: bn,
 			sz: int64(wtr.Len()),
 			md: fi.Mode(),
-			mt: time.Now(), // needs to be more dynamic than fi.ModTime(),
+			mt: modTime,
 		},
 		reader: bytes.NewReader(wtr.Bytes()),
 	}, nil
 }
 
+// renderedEntry returns a directory entry for the rendered file with the given name,
+// like "articles/how.html", taking the mode from info, which describes its source
+// file, and the time that Stat reports for the rendered file, which is the latest of
+// the source and deps. The size is that of the rendered file, which is only rendered
+// when the size is asked for.
+func (vfs *FS) renderedEntry(name string, info fs.FileInfo, deps time.Time) fs.DirEntry {
+	return fs.FileInfoToDirEntry(&lazyFileInfo{
+		fileInfo: fileInfo{nm: path.Base(name), sz: info.Size(), md: info.Mode(), mt: latest(info.ModTime(), deps)},
+		size: func() int64 {
+			fi, err := fs.Stat(vfs, name)
+			if err != nil {
+				slog.Warn("Cannot find size of rendered file", "name", name, "error", err)
+				return info.Size()
+			}
+			return fi.Size()
+		},
+	})
+}
+
 func (vfs *FS) newDirectory(f fs.File, pathname string) (fs.File, error) {
 	rdf, ok := f.(fs.ReadDirFile)
 	if !ok {
@@ -234,11 +355,15 @@ func (vfs *FS) newDirectory(f fs.File, pathname string) (fs.File, error) {
 	if err != nil {
 		return nil, err
 	}
+	deps := vfs.folderModTime(pathname)
 	var vEntries []fs.DirEntry
 	if len(entries) > 0 {
 		vEntries = make([]fs.DirEntry, 0, len(entries))
 	}
 	added := make(map[string]bool)
+	var audio fs.FileInfo
+	// media in page bundles are resources of the page, not pages of their own
+	mediaPages := hasMediaFolderPrefix(pathname) && !vfs.isBundle(pathname)
 	for _, entry := range entries {
 		nm := entry.Name()
 		switch {
@@ -247,6 +372,10 @@ func (vfs *FS) newDirectory(f fs.File, pathname string) (fs.File, error) {
 		case isHiddenFile(nm):
 			continue
 		case strings.HasSuffix(nm, ".md"):
+			// translations are served from language folders
+			if vfs.variantLanguage(nm) != "" {
+				continue
+			}
 			info, err := entry.Info()
 			if err != nil {
 				return nil, err
@@ -254,30 +383,30 @@ func (vfs *FS) newDirectory(f fs.File, pathname string) (fs.File, error) {
 			// new version hides the markdown
 			newNm := strings.TrimSuffix(nm, ".md") + ".html"
 			if _, ok := added[newNm]; !ok {
-				// TODO: info doesn't have the right size because data will be transformed
-				vEntries = append(vEntries, fs.FileInfoToDirEntry(fileInfo{nm: newNm, sz: info.Size(), md: info.Mode(), mt: info.ModTime()}))
+				vEntries = append(vEntries, vfs.renderedEntry(path.Join(pathname, newNm), info, deps))
 				added[newNm] = true
 			}
-		case hasMediaExtension(nm) && hasMediaFolderPrefix(pathname):
+		case hasMediaExtension(nm) && mediaPages:
 			info, err := entry.Info()
 			if err != nil {
 				return nil, err
 			}
+			if hasAudioExtension(nm) && (audio == nil || info.ModTime().After(audio.ModTime())) {
+				audio = info
+			}
 			a := strings.Split(nm, ".")
 			newNm := strings.TrimSuffix(nm, "."+a[len(a)-1]) + ".html"
 			if _, ok := added[newNm]; !ok {
-				// TODO: info doesn't have the right size because data will be transformed
-				vEntries = append(vEntries, fs.FileInfoToDirEntry(fileInfo{nm: newNm, sz: info.Size(), md: info.Mode(), mt: info.ModTime()}))
+				vEntries = append(vEntries, vfs.renderedEntry(path.Join(pathname, newNm), info, deps))
 				added[newNm] = true
 			}
 			vEntries = append(vEntries, entry)
-		case nm == "sitemap.txt":
+		case isSitemap(nm):
 			info, err := entry.Info()
 			if err != nil {
 				return nil, err
 			}
-			// TODO: info doesn't have the right size because data will be transformed
-			vEntries = append(vEntries, fs.FileInfoToDirEntry(fileInfo{nm: nm, sz: info.Size(), md: info.Mode(), mt: info.ModTime()}))
+			vEntries = append(vEntries, vfs.renderedEntry(path.Join(pathname, nm), info, vfs.siteModTime()))
 		default:
 			// Check name just in case of collisions
 			if _, ok := added[nm]; !ok {
@@ -286,6 +415,14 @@ func (vfs *FS) newDirectory(f fs.File, pathname string) (fs.File, error) {
 			}
 		}
 	}
+	// The root folder holds a folder for each additional language
+	if pathname == "." {
+		vEntries = append(vEntries, vfs.languageEntries(added)...)
+	}
+	// Folders with audio files get a podcast feed
+	if audio != nil && !added["feed.xml"] {
+		vEntries = append(vEntries, vfs.renderedEntry(path.Join(pathname, "feed.xml"), audio, deps))
+	}
 	// Sort by filename
 	sort.Slice(vEntries, func(i, j int) bool {
 		return vEntries[i].Name() < vEntries[j].Name()
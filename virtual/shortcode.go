@@ -0,0 +1,252 @@
+package virtual
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// Shortcode is what is passed to shortcode templates.
+type Shortcode struct {
+	Name        string            // name of the shortcode
+	Params      map[string]string // named parameters, like src="dog.png"
+	Args        []string          // positional parameters
+	Inner       template.HTML     // rendered Markdown between the opening and closing shortcode
+	FrontMatter FrontMatter       // front matter of the page
+	Page        PageInfo          // information about the page
+	Site        map[string]any    // site-wide variables
+}
+
+// Get returns a named parameter, or a positional one when given an int.
+func (s Shortcode) Get(key any) string {
+	switch k := key.(type) {
+	case string:
+		return s.Params[k]
+	case int:
+		if k >= 0 && k < len(s.Args) {
+			return s.Args[k]
+		}
+	}
+	return ""
+}
+
+const (
+	shortcodeOpen  = "{{<"
+	shortcodeClose = ">}}"
+	maxShortcodes  = 10 // nesting depth
+)
+
+// renderContent renders the Markdown body of a page into HTML, expanding any shortcodes.
+// Templated pages are run through the template engine after the shortcodes are
+// replaced by tokens, so that the shortcode syntax does not confuse the template parser.
+func (vfs *FS) renderContent(body []byte, d data) template.HTML {
+	sc := shortcodes{vfs: vfs, tpl: vfs.templatesAt(d.depth), data: d}
+	return sc.render(string(body), 0)
+}
+
+// shortcodes expands shortcodes in Markdown, replacing them with tokens that
+// are swapped for the rendered HTML after the Markdown is processed.
+type shortcodes struct {
+	vfs  *FS
+	tpl  *template.Template
+	data data
+	html []string
+	text []string // escaped shortcodes, shown as-is
+}
+
+// render expands the shortcodes in s and converts the result to HTML.
+func (sc *shortcodes) render(s string, depth int) template.HTML {
+	first := len(sc.html)
+	s = sc.expand(s, depth)
+	if sc.data.FrontMatter.Templated {
+		s = sc.vfs.executeMarkdown(s, sc.data)
+	}
+	md := string(blackfriday.Run([]byte(s), blackfriday.WithExtensions(blackfriday.CommonExtensions|blackfriday.Footnotes)))
+	// Markdown puts shortcodes on their own line into paragraphs, which are not
+	// wanted around block content.
+	for i := len(sc.html) - 1; i >= first; i-- {
+		token := sc.token(i)
+		md = strings.ReplaceAll(md, "<p>"+token+"</p>", sc.html[i])
+		md = strings.ReplaceAll(md, token, sc.html[i])
+	}
+	for i := len(sc.text) - 1; i >= 0; i-- {
+		md = strings.ReplaceAll(md, sc.literal(i), template.HTMLEscapeString(sc.text[i]))
+	}
+	return template.HTML(md)
+}
+
+// token returns the placeholder for the given shortcode.
+func (sc *shortcodes) token(i int) string {
+	return fmt.Sprintf("WHISPERSHORTCODE%dX", i)
+}
+
+// literal returns the placeholder for the given escaped shortcode. It keeps the
+// braces away from the template engine of templated pages.
+func (sc *shortcodes) literal(i int) string {
+	return fmt.Sprintf("WHISPERLITERAL%dX", i)
+}
+
+// writeLiteral writes a placeholder for text that is shown as-is.
+func (sc *shortcodes) writeLiteral(out *strings.Builder, text string) {
+	out.WriteString(sc.literal(len(sc.text)))
+	sc.text = append(sc.text, text)
+}
+
+// expand replaces the shortcodes in s with tokens, rendering their templates.
+func (sc *shortcodes) expand(s string, depth int) string {
+	var out strings.Builder
+	for {
+		start := strings.Index(s, shortcodeOpen)
+		if start < 0 {
+			out.WriteString(s)
+			return out.String()
+		}
+		end := strings.Index(s[start:], shortcodeClose)
+		if end < 0 {
+			out.WriteString(s)
+			return out.String()
+		}
+		end += start + len(shortcodeClose)
+		out.WriteString(s[:start])
+		tag := strings.TrimSpace(s[start+len(shortcodeOpen) : end-len(shortcodeClose)])
+		s = s[end:]
+
+		// {{</* name */>}} is an escaped shortcode that is shown as-is
+		if strings.HasPrefix(tag, "/*") && strings.HasSuffix(tag, "*/") {
+			sc.writeLiteral(&out, shortcodeOpen+" "+strings.TrimSpace(tag[2:len(tag)-2])+" "+shortcodeClose)
+			continue
+		}
+		name, args, params := parseShortcode(tag)
+		if name == "" || strings.HasPrefix(name, "/") {
+			sc.writeLiteral(&out, shortcodeOpen+" "+tag+" "+shortcodeClose)
+			continue
+		}
+
+		// find the closing shortcode, if there is one
+		var inner template.HTML
+		if i, j := findClosingShortcode(s, name); i >= 0 {
+			if depth < maxShortcodes {
+				inner = sc.render(s[:i], depth+1)
+			} else {
+				slog.Warn("Shortcodes nested too deeply", "name", name)
+			}
+			s = s[j:]
+		}
+
+		var wtr bytes.Buffer
+		err := sc.tpl.ExecuteTemplate(&wtr, lookupTemplate(sc.tpl, sc.data.Page.Path, "shortcodes/"+name), Shortcode{
+			Name:        name,
+			Params:      params,
+			Args:        args,
+			Inner:       inner,
+			FrontMatter: sc.data.FrontMatter,
+			Page:        sc.data.Page,
+			Site:        sc.data.Site,
+		})
+		if err != nil {
+			slog.Warn("Error executing shortcode template", "name", name, "error", err)
+		}
+		out.WriteString(sc.token(len(sc.html)))
+		sc.html = append(sc.html, wtr.String())
+	}
+}
+
+// findClosingShortcode returns the position of the shortcode closing name in s,
+// and the position just after it, allowing for nested shortcodes with the same name.
+func findClosingShortcode(s, name string) (int, int) {
+	nesting := 0
+	for pos := 0; ; {
+		start := strings.Index(s[pos:], shortcodeOpen)
+		if start < 0 {
+			return -1, -1
+		}
+		start += pos
+		end := strings.Index(s[start:], shortcodeClose)
+		if end < 0 {
+			return -1, -1
+		}
+		end += start + len(shortcodeClose)
+		tag := strings.TrimSpace(s[start+len(shortcodeOpen) : end-len(shortcodeClose)])
+		switch n, _, _ := parseShortcode(tag); n {
+		case name:
+			nesting++
+		case "/" + name:
+			if nesting == 0 {
+				return start, end
+			}
+			nesting--
+		}
+		pos = end
+	}
+}
+
+// parseShortcode splits a shortcode like `figure src="dog.png" "caption"`
+// into its name, positional arguments, and named parameters.
+func parseShortcode(tag string) (string, []string, map[string]string) {
+	type field struct {
+		key, val string
+		named    bool
+	}
+	var (
+		fields []field
+		cur    field
+		buf    strings.Builder
+		quote  rune
+		escape bool
+		inWord bool
+	)
+	// split into fields, honoring quotes; an unquoted equals sign names the parameter
+	for _, c := range tag {
+		switch {
+		case escape:
+			buf.WriteRune(c)
+			escape = false
+		case quote != 0 && c == '\\':
+			escape = true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			buf.WriteRune(c)
+		case c == '"' || c == '\'' || c == '`':
+			quote, inWord = c, true
+		case c == '=' && inWord && !cur.named:
+			cur.key, cur.named = buf.String(), true
+			buf.Reset()
+		case unicode.IsSpace(c):
+			if inWord {
+				cur.val = buf.String()
+				fields = append(fields, cur)
+				cur = field{}
+				buf.Reset()
+				inWord = false
+			}
+		default:
+			buf.WriteRune(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		cur.val = buf.String()
+		fields = append(fields, cur)
+	}
+	if len(fields) == 0 || fields[0].named {
+		return "", nil, nil
+	}
+	var (
+		args   []string
+		params = make(map[string]string)
+	)
+	for _, f := range fields[1:] {
+		if f.named {
+			params[f.key] = f.val
+		} else {
+			args = append(args, f.val)
+		}
+	}
+	return fields[0].val, args, params
+}
@@ -0,0 +1,68 @@
+package virtual
+
+import (
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseShortcode(t *testing.T) {
+	name, args, params := parseShortcode(`figure src="/static/a b.png" "x=y" 'it\'s' width=150`)
+	if name != "figure" {
+		t.Errorf("Expected figure but got %q", name)
+	}
+	if !reflect.DeepEqual(args, []string{"x=y", "it's"}) {
+		t.Errorf("Unexpected args %q", args)
+	}
+	if !reflect.DeepEqual(params, map[string]string{"src": "/static/a b.png", "width": "150"}) {
+		t.Errorf("Unexpected params %q", params)
+	}
+}
+
+func TestShortcodes(t *testing.T) {
+	fileSys, err := New(os.DirFS("../example"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(fileSys, "articles/logo.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(b)
+	for _, expect := range []string{
+		`<figcaption>The Dude</figcaption>`,
+		`<aside class="note"><p>Shortcodes are written like {{&lt; figure`,
+	} {
+		if !strings.Contains(s, expect) {
+			t.Errorf("Expected %q in output", expect)
+		}
+	}
+	if strings.Contains(s, "WHISPERSHORTCODE") || strings.Contains(s, "<p>\n<figure>") {
+		t.Errorf("Shortcode placeholders were not replaced: %s", s)
+	}
+}
+
+func TestEscapedShortcodeTemplated(t *testing.T) {
+	fileSys, err := New(fstest.MapFS{
+		"template/default.html": {Data: []byte(`{{define "default"}}{{.Content}}{{end}}`)},
+		"index.md":              {Data: []byte("+++\ntemplated = true\n+++\nWrite {{</* note */>}} for {{\"a note\"}}.\n\n    {{</* figure src=\"a.png\" */>}}\n")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile(fileSys, "index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`<p>Write {{&lt; note &gt;}} for a note.</p>`,
+		`<pre><code>{{&lt; figure src=&#34;a.png&#34; &gt;}}`,
+	} {
+		if !strings.Contains(string(b), expect) {
+			t.Errorf("Expected %q in %s", expect, b)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"html/template"
 	"io/fs"
+	"log/slog"
 	"path"
 	"strings"
 	"time"
@@ -16,8 +17,11 @@ var defaultTemplate string
 
 // PageInfo has information about the current page.
 type PageInfo struct {
-	Path     string // path from URL
-	Filename string // end portion (file) from URL
+	Path        string // path from URL
+	Filename    string // end portion (file) from URL
+	Number      int    // page number for paginated listings, starting at 1
+	Breadcrumbs []File // index pages of the folders leading to this page, starting at the root
+	pages       *int   // number of pages found by paginate while rendering
 }
 
 // Pathname joins the path and filename.
@@ -27,9 +31,58 @@ func (p PageInfo) Pathname() string {
 
 // data is what is passed to markdown templates.
 type data struct {
-	FrontMatter FrontMatter   // front matter from Markdown file or defaults
-	Page        PageInfo      // information aboout current page
-	Content     template.HTML // rendered Markdown
+	FrontMatter  FrontMatter    // front matter from Markdown file or defaults
+	Page         PageInfo       // information aboout current page
+	Content      template.HTML  // rendered Markdown
+	Media        MediaInfo      // metadata of audio and video files
+	Language     string         // language of the page, if languages are configured
+	Translations []Translation  // versions of the page in each language
+	Site         map[string]any // site-wide variables from the [site] table in whisper.cfg
+	depth        int            // how many pages are being rendered into each other
+}
+
+// maxDepth limits how deeply pages may be rendered into each other using the
+// markdown function, so that a page including itself does not recurse forever.
+const maxDepth = 4
+
+// config returns the settings loaded along with the templates.
+func (vfs *FS) config() *Config {
+	vfs.tplMutex.RLock()
+	defer vfs.tplMutex.RUnlock()
+	return vfs.cfg
+}
+
+// site returns the site-wide variables from the configuration.
+func (vfs *FS) site() map[string]any {
+	if cfg := vfs.config(); cfg != nil {
+		return cfg.Site
+	}
+	return nil
+}
+
+// funcMap returns the functions available to templates.
+func (vfs *FS) funcMap() template.FuncMap {
+	vfs.tplMutex.RLock()
+	defer vfs.tplMutex.RUnlock()
+	return vfs.funcs
+}
+
+// executeMarkdown runs the Markdown of a templated page through html/template,
+// returning the Markdown unchanged if the template fails.
+func (vfs *FS) executeMarkdown(md string, d data) string {
+	name := d.Page.Pathname()
+	tpl, err := template.New(name).Funcs(vfs.funcMap()).Funcs(template.FuncMap{"markdown": vfs.markdownAt(d.depth)}).Parse(md)
+	if err != nil {
+		slog.Warn("Error parsing templated markdown", "page", name, "error", err)
+		return md
+	}
+	var wtr strings.Builder
+	err = tpl.Execute(&wtr, d)
+	if err != nil {
+		slog.Warn("Error executing templated markdown", "page", name, "error", err)
+		return md
+	}
+	return wtr.String()
 }
 
 // getTemplates returns the templates and last time they were modified.
@@ -39,29 +92,146 @@ func (vfs *FS) getTemplates() *template.Template {
 	return vfs.tpl
 }
 
+// templatesAt returns the templates for rendering a page included at the given
+// depth, whose markdown function renders at the next depth.
+func (vfs *FS) templatesAt(depth int) *template.Template {
+	vfs.tplMutex.RLock()
+	defer vfs.tplMutex.RUnlock()
+	if depth > 0 && depth <= len(vfs.nested) {
+		return vfs.nested[depth-1]
+	}
+	return vfs.tpl
+}
+
+// nestTemplates clones the templates for each depth of included pages. It must be
+// called before the templates are executed.
+func (vfs *FS) nestTemplates(tpl *template.Template) ([]*template.Template, error) {
+	nested := make([]*template.Template, maxDepth)
+	for i := range nested {
+		t, err := tpl.Clone()
+		if err != nil {
+			return nil, err
+		}
+		nested[i] = t.Funcs(template.FuncMap{"markdown": vfs.markdownAt(i + 1)})
+	}
+	return nested, nil
+}
+
+// lookupTemplate finds the template with the given name for a page in folder, preferring
+// templates in matching subfolders of the template folder. For example, a page in
+// "articles/2024" uses "articles/2024/default", then "articles/default", then "default".
+func lookupTemplate(tpl *template.Template, folder, name string) string {
+	folder = strings.Trim(path.Clean("/"+folder), "/")
+	for folder != "" && folder != "." {
+		if n := path.Join(folder, name); tpl.Lookup(n) != nil {
+			return n
+		}
+		folder = path.Dir(folder)
+	}
+	return name
+}
+
+// parseTemplateFolders parses templates found in subfolders of the template folder.
+// Each file is a template named by its path without the extension, like "articles/default".
+// Templates defined in the files are added too, unless they would replace one that
+// already exists, like "default", which would change it for the whole site.
+func (vfs *FS) parseTemplateFolders(tpl *template.Template, funcMap template.FuncMap) error {
+	return fs.WalkDir(vfs.fs, "template", func(pathname string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Dir(pathname) == "template" || path.Ext(pathname) != ".html" {
+			return nil
+		}
+		b, err := fs.ReadFile(vfs.fs, pathname)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(pathname, "template/"), ".html")
+		t, err := template.New(name).Funcs(funcMap).Parse(string(b))
+		if err != nil {
+			return err
+		}
+		for _, def := range t.Templates() {
+			if def.Tree == nil {
+				continue
+			}
+			if tpl.Lookup(def.Name()) != nil {
+				return fmt.Errorf("%s: template %q is already defined", pathname, def.Name())
+			}
+			if _, err = tpl.AddParseTree(def.Name(), def.Tree); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 // loadTemplates loads and parses the HTML templates, returning true if custom templates were found.
 func (vfs *FS) loadTemplates() (bool, error) {
 	var err error
 	funcMap := template.FuncMap{
-		"dir":         vfs.dir,
-		"sortbyname":  sortByName,
-		"sortbytime":  sortByTime,
-		"match":       match,
-		"filter":      filter,
-		"join":        path.Join,
-		"ext":         path.Ext,
-		"prev":        prev,
-		"next":        next,
-		"reverse":     reverse,
-		"trimsuffix":  strings.TrimSuffix,
-		"trimprefix":  strings.TrimPrefix,
-		"trimspace":   strings.TrimSpace,
-		"markdown":    vfs.md,
-		"frontmatter": vfs.fm,
-		"now":         time.Now,
+		"dir":          vfs.dir,
+		"sortbyname":   sortByName,
+		"sortbytime":   sortByTime,
+		"sortbyweight": sortByWeight,
+		"match":        match,
+		"filter":       filter,
+		"join":         path.Join,
+		"ext":          path.Ext,
+		"prev":         prev,
+		"next":         next,
+		"paginate":     paginate,
+		"series":       vfs.series,
+		"tree":         vfs.tree,
+		"related":      vfs.related,
+		"translations": vfs.translations,
+		"reverse":      reverse,
+		"trimsuffix":   strings.TrimSuffix,
+		"trimprefix":   strings.TrimPrefix,
+		"trimspace":    strings.TrimSpace,
+		"markdown":     vfs.markdownAt(0),
+		"frontmatter":  vfs.fm,
+		"now":          time.Now,
+		"date":         date,
+		"wordcount":    wordcount,
+		"readingtime":  readingtime,
+		"truncatehtml": truncatehtml,
+		"summary":      summary,
+		"slugify":      slugify,
+		"where":        where,
+		"sortby":       sortby,
+		"first":        first,
+		"last":         last,
+		"limit":        limit,
+		"add":          add,
+		"sub":          sub,
+		"mul":          mul,
+		"div":          div,
+		"mod":          mod,
+		"dict":         dict,
+		"slice":        slice,
+		"absurl":       vfs.absurl,
+		"data":         vfs.dataFile,
+		"resources":    vfs.resources,
+		"asset":        vfs.asset,
+	}
+	cfg, err := vfs.Config()
+	if err != nil {
+		slog.Warn("loadTemplates cannot load config; keeping the previous one", "error", err)
+		cfg = vfs.config()
+		if cfg == nil {
+			cfg = &Config{}
+		}
 	}
+	dataFiles := vfs.loadData()
+	modTime := vfs.loadModTime()
 	vfs.tplMutex.Lock()
 	defer vfs.tplMutex.Unlock()
+	vfs.cfg = cfg
+	vfs.funcs = funcMap
+	vfs.dataFiles = dataFiles
+	vfs.tplModTime = modTime
 	// Check if we are using default templates
 	fi, err := fs.Stat(vfs.fs, "template")
 	if errors.Is(err, fs.ErrNotExist) || (err == nil && !fi.IsDir()) {
@@ -69,7 +239,11 @@ func (vfs *FS) loadTemplates() (bool, error) {
 		if err != nil {
 			return false, fmt.Errorf("loadTemplates: %w", err)
 		}
-		vfs.tpl = tpl
+		nested, err := vfs.nestTemplates(tpl)
+		if err != nil {
+			return false, fmt.Errorf("loadTemplates: %w", err)
+		}
+		vfs.tpl, vfs.nested = tpl, nested
 		return false, nil
 	}
 	// use custom templates
@@ -77,6 +251,14 @@ func (vfs *FS) loadTemplates() (bool, error) {
 	if err != nil {
 		return true, fmt.Errorf("loadTemplates: %w", err)
 	}
-	vfs.tpl = tpl
+	err = vfs.parseTemplateFolders(tpl, funcMap)
+	if err != nil {
+		return true, fmt.Errorf("loadTemplates: %w", err)
+	}
+	nested, err := vfs.nestTemplates(tpl)
+	if err != nil {
+		return true, fmt.Errorf("loadTemplates: %w", err)
+	}
+	vfs.tpl, vfs.nested = tpl, nested
 	return true, nil
 }
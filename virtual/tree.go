@@ -0,0 +1,126 @@
+package virtual
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Node is a folder in the site tree. The embedded File describes the folder's
+// index page, with the title and weight taken from its index.md front matter.
+// Filename is empty for folders without an index page.
+type Node struct {
+	File
+	Children []*Node // subfolders, sorted by weight in reverse order like sortbyweight, and then title
+}
+
+// tree returns the folder hierarchy starting at the given folder and is used in templates.
+// The tree is kept until the content or templates change.
+func (vfs *FS) tree(folderpath string) *Node {
+	folderpath = path.Clean("./" + strings.TrimPrefix(folderpath, "/"))
+	key := vfs.indexKey()
+	idx := &vfs.index
+	idx.mutex.Lock()
+	idx.update(key)
+	n, ok := idx.trees[folderpath]
+	idx.mutex.Unlock()
+	if ok {
+		return n
+	}
+	fi, err := fs.Stat(vfs.fs, folderpath)
+	if err != nil || !fi.IsDir() || isHiddenFile(folderpath) || (folderpath != "." && containsSpecialFile(folderpath)) {
+		slog.Error("tree: folder not found", "path", folderpath)
+		return nil
+	}
+	n = vfs.treeNode(folderpath)
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if idx.key == key {
+		if idx.trees == nil {
+			idx.trees = make(map[string]*Node)
+		}
+		idx.trees[folderpath] = n
+	}
+	return n
+}
+
+// treeNode returns the Node for the given folder, including its subfolders.
+func (vfs *FS) treeNode(folderpath string) *Node {
+	n := &Node{File: vfs.folderFile(folderpath)}
+	if !vfs.hasIndex(folderpath) {
+		n.Filename = ""
+	}
+	entries, err := fs.ReadDir(vfs.fs, folderpath)
+	if err != nil {
+		slog.Error("tree: ReadDir failed", "error", err)
+		return n
+	}
+	for _, entry := range entries {
+		nm := path.Join(folderpath, entry.Name())
+		if !entry.IsDir() || isHiddenFile(nm) || containsSpecialFile(nm) {
+			continue
+		}
+		// leave out folders that have no pages to link to
+		if child := vfs.treeNode(nm); child.Filename != "" || len(child.Children) > 0 {
+			n.Children = append(n.Children, child)
+		}
+	}
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		a, b := n.Children[i].FrontMatter, n.Children[j].FrontMatter
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+		return a.Title < b.Title
+	})
+	return n
+}
+
+// folderFile returns a File for the index page of the given folder. The title is
+// the folder name unless the index.md front matter provides one.
+func (vfs *FS) folderFile(folderpath string) File {
+	fm := FrontMatter{Title: path.Base(folderpath)}
+	if folderpath == "." {
+		fm.Title = "Home"
+	}
+	err := vfs.readFrontMatter(vfs.markdownFor(path.Join(folderpath, "index.html")), &fm)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("folderFile problem reading front matter", "error", err)
+	}
+	return File{FrontMatter: fm, Filename: "index.html", Path: folderURL(folderpath)}
+}
+
+// breadcrumbs returns the index pages of the folders leading to the page at pathname,
+// starting at the root. An index page is not included in its own breadcrumbs, and
+// folders without an index page are skipped.
+func (vfs *FS) breadcrumbs(pathname string) []File {
+	pathname = strings.TrimPrefix(path.Clean("/"+pathname), "/")
+	folder := path.Dir(pathname)
+	if path.Base(pathname) == "index.html" {
+		if folder == "." {
+			return nil
+		}
+		folder = path.Dir(folder)
+	}
+	var folders []string
+	for ; folder != "."; folder = path.Dir(folder) {
+		folders = append(folders, folder)
+	}
+	folders = append(folders, ".")
+	var crumbs []File
+	for i := len(folders) - 1; i >= 0; i-- {
+		if !vfs.hasIndex(folders[i]) {
+			continue
+		}
+		crumbs = append(crumbs, vfs.folderFile(folders[i]))
+	}
+	return crumbs
+}
+
+// hasIndex returns true if the folder has an index page.
+func (vfs *FS) hasIndex(folderpath string) bool {
+	_, err := fs.Stat(vfs.fs, vfs.markdownFor(path.Join(folderpath, "index.html")))
+	return err == nil
+}
@@ -0,0 +1,203 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// accessEntryKey is the context key of the accessEntry of a request.
+type accessEntryKey struct{}
+
+// accessEntry holds what inner handlers learn about a request for the access log.
+type accessEntry struct {
+	cache  string // "hit" or "miss", if known
+	loaded bool   // whether a file was loaded for the request, guarded by the LoadTracker
+}
+
+// AccessLogHandler logs requests to out in the given format, which is "text" or
+// "json" for slog records, or "combined" for the Combined Log Format. Only the
+// given fraction of requests are logged, or all of them if sample is not between
+// 0 and 1, but server errors are always logged. Requests for paths matching any of
+// the exclude patterns are not logged. A pattern ending in "/" matches the folder
+// and everything in it, and other patterns are matched using path.Match.
+func AccessLogHandler(h http.Handler, out io.Writer, format string, sample float64, exclude []string) http.Handler {
+	var (
+		logger *slog.Logger
+		mutex  sync.Mutex
+	)
+	switch format {
+	case "json":
+		logger = slog.New(slog.NewJSONHandler(out, nil))
+	case "combined":
+	default:
+		logger = slog.New(slog.NewTextHandler(out, nil))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isExcluded(r.URL.Path, exclude) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		entry := &accessEntry{}
+		sw := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, entry)))
+		if sample > 0 && sample < 1 && sw.Status() < 500 && rand.Float64() >= sample {
+			return
+		}
+		d := time.Since(start)
+		cache := entry.cache
+		if logger != nil {
+			logger.LogAttrs(r.Context(), slog.LevelInfo, "request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", sw.Status()),
+				slog.Int64("bytes", sw.bytes),
+				slog.Duration("duration", d),
+				slog.String("referer", r.Referer()),
+				slog.String("useragent", r.UserAgent()),
+				slog.String("cache", cache),
+			)
+			return
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		fmt.Fprintln(out, combinedLogLine(r, sw.Status(), sw.bytes, start, d, cache))
+	})
+}
+
+// combinedLogLine formats a request in the Combined Log Format, followed by the
+// duration in seconds and the cache status.
+func combinedLogLine(r *http.Request, status int, bytes int64, start time.Time, d time.Duration, cache string) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	user := "-"
+	if u := r.URL.User; u != nil && u.Username() != "" {
+		user = u.Username()
+	}
+	size := "-"
+	if bytes > 0 {
+		size = fmt.Sprint(bytes)
+	}
+	if cache == "" {
+		cache = "-"
+	}
+	return fmt.Sprintf("%s - %s [%s] %q %d %s %q %q %.3f %s",
+		host, user, start.Format("02/Jan/2006:15:04:05 -0700"),
+		r.Method+" "+r.URL.RequestURI()+" "+r.Proto, status, size,
+		orDash(r.Referer()), orDash(r.UserAgent()), d.Seconds(), cache)
+}
+
+// orDash returns s, or "-" if it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// isExcluded returns true if the path matches any of the patterns.
+func isExcluded(p string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/") {
+			if strings.HasPrefix(p, pattern) {
+				return true
+			}
+		} else if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadTracker wraps the file system behind a cache, noting which requests in
+// progress needed a file to be loaded, so that CacheStatusHandler can tell cache
+// hits from misses. It only remembers requests while they are served.
+type LoadTracker struct {
+	fsys    fs.FS
+	mutex   sync.Mutex
+	waiting map[string][]*accessEntry // requests in progress by file name
+}
+
+// TrackLoads returns a LoadTracker wrapping fsys.
+func TrackLoads(fsys fs.FS) *LoadTracker {
+	return &LoadTracker{fsys: fsys, waiting: make(map[string][]*accessEntry)}
+}
+
+// Open opens the named file, marking the requests waiting for it as misses.
+func (t *LoadTracker) Open(name string) (fs.File, error) {
+	t.mutex.Lock()
+	for _, entry := range t.waiting[name] {
+		entry.loaded = true
+	}
+	t.mutex.Unlock()
+	return t.fsys.Open(name)
+}
+
+// wait notes that the request is waiting for the named files.
+func (t *LoadTracker) wait(entry *accessEntry, names []string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, name := range names {
+		t.waiting[name] = append(t.waiting[name], entry)
+	}
+}
+
+// done forgets the request, returning whether any of the named files were loaded for it.
+func (t *LoadTracker) done(entry *accessEntry, names []string) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, name := range names {
+		entries := slices.DeleteFunc(t.waiting[name], func(e *accessEntry) bool { return e == entry })
+		if len(entries) == 0 {
+			delete(t.waiting, name)
+		} else {
+			t.waiting[name] = entries
+		}
+	}
+	return entry.loaded
+}
+
+// CacheStatusHandler reports to AccessLogHandler whether the requested file was in
+// the cache, by checking whether it was loaded through t while h served it. It
+// must come after handlers that change the path, like LanguageHandler.
+func CacheStatusHandler(h http.Handler, t *LoadTracker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entry, ok := r.Context().Value(accessEntryKey{}).(*accessEntry)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if name == "" {
+			name = "."
+		}
+		names := []string{name}
+		if strings.HasSuffix(r.URL.Path, "/") {
+			name = path.Join(name, "index.html")
+			names = append(names, name)
+		}
+		for _, enc := range encodings {
+			names = append(names, name+enc.ext)
+		}
+		t.wait(entry, names)
+		h.ServeHTTP(w, r)
+		if t.done(entry, names) {
+			entry.cache = "miss"
+		} else {
+			entry.cache = "hit"
+		}
+	})
+}
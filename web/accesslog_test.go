@@ -0,0 +1,76 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestAccessLogHandler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":    {Data: []byte("<h1>Home</h1>")},
+		"static/a.css":  {Data: []byte("p{}")},
+		"articles/x.md": {Data: []byte("# X")},
+	}
+	tracker := TrackLoads(fsys)
+	var out bytes.Buffer
+	h := AccessLogHandler(CacheStatusHandler(http.FileServer(http.FS(tracker)), tracker), &out, "json", 0, []string{"/static/", "/*.ico"})
+
+	get := func(p string) {
+		r := httptest.NewRequest(http.MethodGet, p, nil)
+		r.Header.Set("Referer", "https://example.com/")
+		r.Header.Set("User-Agent", "test")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	get("/")
+	get("/static/a.css")
+	get("/favicon.ico")
+	var rec map[string]any
+	if err := json.Unmarshal(out.Bytes(), &rec); err != nil {
+		t.Fatalf("Expected one JSON record but got %q: %v", out.String(), err)
+	}
+	if rec["method"] != "GET" || rec["path"] != "/" || rec["status"] != 200.0 || rec["bytes"] != 13.0 ||
+		rec["referer"] != "https://example.com/" || rec["useragent"] != "test" || rec["cache"] != "miss" {
+		t.Errorf("Unexpected record %v", rec)
+	}
+
+	// the same file is not loaded again through a cache, but it is here
+	out.Reset()
+	h = AccessLogHandler(CacheStatusHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), tracker), &out, "text", 0, nil)
+	get("/")
+	if !strings.Contains(out.String(), "cache=hit") {
+		t.Errorf("Expected a cache hit in %q", out.String())
+	}
+	if len(tracker.waiting) != 0 {
+		t.Errorf("Expected finished requests to be forgotten but got %v", tracker.waiting)
+	}
+
+	// sampling skips some requests, but not server errors
+	out.Reset()
+	h = AccessLogHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}), &out, "text", 0.000001, nil)
+	for i := 0; i < 3; i++ {
+		get("/")
+	}
+	if n := strings.Count(out.String(), "\n"); n != 3 {
+		t.Errorf("Expected all server errors to be logged but got %d", n)
+	}
+}
+
+func TestCombinedLogLine(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/a.html?x=1", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("User-Agent", "Mozilla/5.0")
+	start := time.Date(2024, 3, 5, 15, 4, 5, 0, time.UTC)
+	s := combinedLogLine(r, 200, 1024, start, 1500*time.Millisecond, "hit")
+	expect := `192.0.2.1 - - [05/Mar/2024:15:04:05 +0000] "GET /a.html?x=1 HTTP/1.1" 200 1024 "-" "Mozilla/5.0" 1.500 hit`
+	if s != expect {
+		t.Errorf("Expected\n%s\nbut got\n%s", expect, s)
+	}
+}
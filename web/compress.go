@@ -0,0 +1,115 @@
+package web
+
+import (
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// encodings are the content codings supported by CompressHandler in order of
+// preference, with the extension of the compressed variant of a file.
+var encodings = []struct {
+	name string
+	ext  string
+}{
+	{"br", ".br"},
+	{"zstd", ".zst"},
+	{"gzip", ".gz"},
+}
+
+// CompressHandler serves a compressed variant of the requested file when the client
+// accepts it, preferring Brotli, then Zstandard, then gzip. A variant is a file with
+// the same name and an extension of ".br", ".zst", or ".gz", like "site.css.br". It may
+// be precompressed on disk, or made by the virtual file system and kept in its cache,
+// so responses are never compressed on each request. Requests for other files, or
+// that do not accept any of the encodings, are passed to h.
+func CompressHandler(h http.Handler, fsys fs.FS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			h.ServeHTTP(w, r)
+			return
+		}
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if strings.HasSuffix(r.URL.Path, "/") {
+			name = path.Join(name, "index.html")
+		}
+		ctype := mime.TypeByExtension(path.Ext(name))
+		if name == "" || !isCompressible(ctype) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		fi, err := fs.Stat(fsys, name)
+		if err != nil || !fi.Mode().IsRegular() {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		accepted := acceptedEncodings(r.Header.Get("Accept-Encoding"))
+		for _, enc := range encodings {
+			if ok, listed := accepted[enc.name]; !ok && (listed || !accepted["*"]) {
+				continue
+			}
+			f, err := fsys.Open(name + enc.ext)
+			if err != nil {
+				continue
+			}
+			defer f.Close()
+			rs, ok := f.(io.ReadSeeker)
+			if !ok {
+				continue
+			}
+			w.Header().Set("Content-Type", ctype)
+			w.Header().Set("Content-Encoding", enc.name)
+			// each encoding is a different representation with its own entity tag
+			if tag := w.Header().Get("ETag"); strings.HasSuffix(tag, `"`) {
+				w.Header().Set("ETag", strings.TrimSuffix(tag, `"`)+"-"+enc.name+`"`)
+			}
+			http.ServeContent(w, r, name, fi.ModTime(), rs)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// acceptedEncodings returns the content codings in an Accept-Encoding header
+// that are not refused with a quality of zero.
+func acceptedEncodings(header string) map[string]bool {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(k, "q") {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		accepted[coding] = q > 0
+	}
+	return accepted
+}
+
+// isCompressible returns true for content types that are worth compressing, like
+// text, scripts, and SVG images. Other files, like photos and video, are passed on
+// without looking for compressed variants, which would be missed on every request.
+func isCompressible(ctype string) bool {
+	ctype, _, _ = strings.Cut(ctype, ";")
+	if strings.HasPrefix(ctype, "text/") || strings.HasSuffix(ctype, "+xml") || strings.HasSuffix(ctype, "+json") {
+		return true
+	}
+	switch ctype {
+	case "application/javascript", "application/json", "application/xml", "application/wasm":
+		return true
+	}
+	return false
+}
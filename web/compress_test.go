@@ -0,0 +1,82 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestAcceptedEncodings(t *testing.T) {
+	a := acceptedEncodings("gzip, br;q=0.5, zstd;q=0, *;q=0.1")
+	if !a["gzip"] || !a["br"] || a["zstd"] || !a["*"] {
+		t.Errorf("Unexpected encodings %v", a)
+	}
+}
+
+func TestCompressHandler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"site.css":        {Data: []byte("body { color: red }"), ModTime: time.Now()},
+		"site.css.br":     {Data: []byte("brotli")},
+		"site.css.gz":     {Data: []byte("gzip")},
+		"index.html":      {Data: []byte("<h1>Home</h1>")},
+		"index.html.gz":   {Data: []byte("gzip")},
+		"photo.png":       {Data: []byte("png")},
+		"photo.png.gz":    {Data: []byte("gzip")},
+		"logo.svg":        {Data: []byte("<svg></svg>")},
+		"logo.svg.gz":     {Data: []byte("gzip")},
+		"doc.pdf":         {Data: []byte("pdf")},
+		"doc.pdf.gz":      {Data: []byte("gzip")},
+		"articles/a.html": {Data: []byte("<h1>A</h1>")},
+	}
+	h := CompressHandler(http.FileServer(http.FS(fsys)), fsys)
+	tests := []struct {
+		path, accept, encoding, body string
+	}{
+		{"/site.css", "gzip, deflate, br, zstd", "br", "brotli"},
+		{"/site.css", "gzip", "gzip", "gzip"},
+		{"/site.css", "br;q=0, gzip", "gzip", "gzip"},
+		{"/site.css", "*", "br", "brotli"},
+		{"/site.css", "", "", "body { color: red }"},
+		{"/", "br, gzip", "gzip", "gzip"},
+		{"/photo.png", "gzip", "", "png"},
+		{"/logo.svg", "gzip", "gzip", "gzip"},
+		{"/doc.pdf", "gzip", "", "pdf"},
+		{"/articles/a.html", "gzip", "", "<h1>A</h1>"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.accept != "" {
+			r.Header.Set("Accept-Encoding", test.accept)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if enc := w.Header().Get("Content-Encoding"); enc != test.encoding {
+			t.Errorf("Expected encoding %q for %s with %q but got %q", test.encoding, test.path, test.accept, enc)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("Unexpected body %q for %s with %q", w.Body.String(), test.path, test.accept)
+		}
+	}
+
+	// compressed variants get their own ETag
+	h = ETagHandler(h, fsys)
+	r := httptest.NewRequest(http.MethodGet, "/site.css", nil)
+	r.Header.Set("Accept-Encoding", "br")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	tag := w.Header().Get("ETag")
+	if expect, _ := etag(fsys, "site.css"); tag != expect[:len(expect)-1]+`-br"` {
+		t.Errorf("Unexpected ETag %q", tag)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/css; charset=utf-8" {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+	r.Header.Set("If-None-Match", tag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 but got %d", w.Code)
+	}
+}
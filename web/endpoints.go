@@ -0,0 +1,16 @@
+package web
+
+import "net/http"
+
+// EndpointHandler serves requests for the given paths, like "/metrics", using the
+// matching handler, and passes other requests to h. The endpoints hide any files
+// with the same names.
+func EndpointHandler(h http.Handler, endpoints map[string]http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if e, ok := endpoints[r.URL.Path]; ok {
+			e.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,56 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// ETagHandler sets a strong ETag header from a hash of the content of the requested
+// file in fsys, so that http.FileServer answers matching If-None-Match requests with
+// 304 Not Modified. The content is read from fsys, which is meant to be the cache, so
+// rendered pages are not rendered again, and two different bodies never share an ETag.
+func ETagHandler(h http.Handler, fsys fs.FS) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			h.ServeHTTP(w, r)
+			return
+		}
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		if strings.HasSuffix(r.URL.Path, "/") {
+			name = path.Join(name, "index.html")
+		}
+		if name == "" {
+			name = "."
+		}
+		if tag, err := etag(fsys, name); err == nil {
+			w.Header().Set("ETag", tag)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// etag returns a strong entity tag for the content of the named regular file.
+func etag(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	if !fi.Mode().IsRegular() {
+		return "", fs.ErrInvalid
+	}
+	hash := sha256.New()
+	if _, err = io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`, nil
+}
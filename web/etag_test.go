@@ -0,0 +1,53 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestETagHandler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":        {Data: []byte("<h1>Home</h1>"), ModTime: time.Now()},
+		"articles/how.html": {Data: []byte("<h1>How</h1>"), ModTime: time.Now()},
+	}
+	h := ETagHandler(http.FileServer(http.FS(fsys)), fsys)
+	tagOf := func(name string) string {
+		tag, err := etag(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tag
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/articles/how.html", nil))
+	tag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || tag != tagOf("articles/how.html") {
+		t.Fatalf("Unexpected response %d with ETag %q", w.Code, tag)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/articles/how.html", nil)
+	r.Header.Set("If-None-Match", tag)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 but got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if tag := w.Header().Get("ETag"); tag != tagOf("index.html") {
+		t.Errorf("Unexpected ETag %q for folder", tag)
+	}
+
+	// a change of content changes the ETag, even with the same size and time
+	fsys["articles/how.html"] = &fstest.MapFile{Data: []byte("<h1>Who</h1>"), ModTime: fsys["articles/how.html"].ModTime}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == tag {
+		t.Errorf("Expected a new ETag but got %d with %q", w.Code, w.Header().Get("ETag"))
+	}
+}
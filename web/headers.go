@@ -1,12 +1,23 @@
 package web
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 )
 
+// fingerprinted matches content-hashed asset names like "site.0123456789abcdef.css",
+// as produced by the asset template function of the virtual package.
+var fingerprinted = regexp.MustCompile(`\.[0-9a-f]{16}\.[^./]+$`)
+
+// immutable is the Cache-Control header for fingerprinted assets, which never change.
+const immutable = "public, max-age=31536000, immutable"
+
 var gmtZone *time.Location
 
 func init() {
@@ -17,28 +28,211 @@ func init() {
 	}
 }
 
-// HeaderHandler returns an http.Handler that adds the given headers to the response.
-func HeaderHandler(h http.Handler, headers map[string]string) http.Handler {
+// HeaderRule is a rule for HeaderHandler that sets headers for some requests.
+// Path matches the request path using the syntax of Redirect, like "/videos/*",
+// and Type matches the content type of the response, like "text/html" or "image/*".
+// When both are given, both must match.
+type HeaderRule struct {
+	Path   string            // Path to match, or empty to match any path
+	Type   string            // Content type to match, or empty to match any type
+	Values map[string]string // Headers to set; an empty value removes the header
+}
+
+// HeaderHandler returns an http.Handler that adds the given headers to the response,
+// followed by the values of the matching rules in order. Rules are applied when the
+// response is written, once the content type is known, so that they override the
+// headers set by h, like Cache-Control.
+func HeaderHandler(h http.Handler, headers map[string]string, rules []HeaderRule) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		for k, v := range headers {
 			w.Header().Set(k, v)
 		}
+		var matched []HeaderRule
+		for _, rule := range rules {
+			if rule.Path != "" {
+				if _, ok := matchPath(rule.Path, r.URL.Path); !ok {
+					continue
+				}
+			}
+			matched = append(matched, rule)
+		}
+		if len(matched) > 0 {
+			w = &ruleHeaderWriter{ResponseWriter: w, rules: matched}
+		}
 		h.ServeHTTP(w, r)
 	})
 }
 
+// setHeaders sets the headers, removing those with empty values.
+func setHeaders(header http.Header, values map[string]string) {
+	for k, v := range values {
+		if v == "" {
+			header.Del(k)
+		} else {
+			header.Set(k, v)
+		}
+	}
+}
+
+// matchType reports whether the content type matches the pattern, like
+// "text/html" or "image/*", ignoring parameters like the charset.
+func matchType(pattern, ctype string) bool {
+	ctype, _, _ = strings.Cut(ctype, ";")
+	ctype = strings.ToLower(strings.TrimSpace(ctype))
+	pattern = strings.ToLower(pattern)
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(ctype, prefix)
+	}
+	return ctype == pattern
+}
+
+// ruleHeaderWriter applies the rules matching the path once the response headers
+// are written, skipping those for other content types.
+type ruleHeaderWriter struct {
+	http.ResponseWriter
+	rules []HeaderRule
+	wrote bool
+}
+
+// WriteHeader sets the headers of the rules without a content type or matching
+// it, unless the status is informational.
+func (w *ruleHeaderWriter) WriteHeader(code int) {
+	if !w.wrote && code >= http.StatusOK {
+		w.wrote = true
+		ctype := w.Header().Get("Content-Type")
+		for _, rule := range w.rules {
+			if rule.Type == "" || matchType(rule.Type, ctype) {
+				setHeaders(w.Header(), rule.Values)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes the headers first, detecting the content type if it is not set.
+func (w *ruleHeaderWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		if _, ok := w.Header()["Content-Type"]; !ok {
+			w.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// ReadFrom writes the headers first, and lets the underlying ResponseWriter use
+// sendfile. If the content type is not set, the first bytes go through Write to
+// detect it.
+func (w *ruleHeaderWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !w.wrote {
+		if _, ok := w.Header()["Content-Type"]; !ok {
+			return io.Copy(struct{ io.Writer }{w}, r)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	return io.Copy(w.ResponseWriter, r)
+}
+
+// Flush writes the headers first and flushes the underlying ResponseWriter.
+func (w *ruleHeaderWriter) Flush() {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *ruleHeaderWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // ExpiresHandler adds the expires header choosing expires for dynamic content
-// and staticExpires for static content.
+// and staticExpires for static content. Fingerprinted assets that are found are
+// cached for a year and marked immutable.
 func ExpiresHandler(h http.Handler, expires, staticExpires time.Duration) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		expiry := staticExpires
-		if strings.HasSuffix(r.URL.Path, "/") || strings.HasSuffix(r.URL.Path, ".html") || r.URL.Path == "/sitemap.txt" {
+		if strings.HasSuffix(r.URL.Path, "/") || strings.HasSuffix(r.URL.Path, ".html") || r.URL.Path == "/sitemap.txt" || r.URL.Path == "/sitemap.xml" || strings.HasSuffix(r.URL.Path, "/feed.xml") {
 			expiry = expires
 		}
 		if expiry != 0 {
 			// w.Header().Set("Expires", time.Now().Add(expiry).In(gmtZone).Format(time.RFC1123))
 			w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int64(expiry.Seconds())))
 		}
+		if fingerprinted.MatchString(r.URL.Path) {
+			w = &immutableWriter{ResponseWriter: w}
+		}
 		h.ServeHTTP(w, r)
 	})
 }
+
+// immutableWriter marks successful responses as immutable, so that errors
+// like a missing asset are not cached for a year.
+type immutableWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+// WriteHeader sets the immutable Cache-Control header for successful responses.
+func (w *immutableWriter) WriteHeader(code int) {
+	if !w.wrote && code >= http.StatusOK {
+		w.wrote = true
+		if code < http.StatusMultipleChoices || code == http.StatusNotModified {
+			w.Header().Set("Cache-Control", immutable)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write writes the headers first.
+func (w *immutableWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// ReadFrom writes the headers first, and lets the underlying ResponseWriter use sendfile.
+func (w *immutableWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	return io.Copy(w.ResponseWriter, r)
+}
+
+// Flush writes the headers first and flushes the underlying ResponseWriter.
+func (w *immutableWriter) Flush() {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *immutableWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// AssetRedirectHandler redirects requests for fingerprinted assets that are not
+// in fsys, because their content has changed, to the current fingerprinted URL
+// given by current. This keeps pages that refer to an older version working.
+func AssetRedirectHandler(h http.Handler, fsys fs.FS, current func(pathname string) (string, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		if !fingerprinted.MatchString(p) || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if _, err := fs.Stat(fsys, strings.TrimPrefix(p, "/")); !errors.Is(err, fs.ErrNotExist) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		u, err := current(p)
+		if err != nil || u == p {
+			h.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		http.Redirect(w, r, u, http.StatusFound)
+	})
+}
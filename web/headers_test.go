@@ -0,0 +1,111 @@
+package web
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestExpiresHandler(t *testing.T) {
+	h := ExpiresHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/static/old.") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("content"))
+	}), time.Minute, time.Hour)
+	tests := map[string]string{
+		"/":                                 "max-age=60",
+		"/articles/how.html":                "max-age=60",
+		"/static/site.css":                  "max-age=3600",
+		"/static/site.0123456789abcdef.css": immutable,
+		"/static/site.0123456789abcdeg.css": "max-age=3600",
+		"/static/old.0123456789abcdef.css":  "max-age=3600",
+	}
+	for p, expect := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, p, nil))
+		if cc := w.Header().Get("Cache-Control"); cc != expect {
+			t.Errorf("Expected %q for %s but got %q", expect, p, cc)
+		}
+	}
+}
+
+func TestHeaderHandler(t *testing.T) {
+	h := HeaderHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/logo.png" {
+			w.Header().Set("Content-Type", "image/png")
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		if r.URL.RawQuery == "copy" {
+			// like http.ServeContent, which copies with ReadFrom
+			w.(io.ReaderFrom).ReadFrom(strings.NewReader("<html></html>"))
+			return
+		}
+		w.Write([]byte("<html></html>"))
+	}), map[string]string{"Content-Security-Policy": "default-src 'self'", "X-Frame-Options": "DENY"}, []HeaderRule{
+		{Path: "/videos/*", Values: map[string]string{"Content-Security-Policy": "frame-src https://www.youtube.com"}},
+		{Path: "/embed.html", Values: map[string]string{"X-Frame-Options": "", "Cache-Control": "no-store"}},
+		{Type: "image/*", Values: map[string]string{"Content-Security-Policy": "", "Cache-Control": "max-age=3600"}},
+		{Path: "/videos/*", Type: "text/html", Values: map[string]string{"X-Video": "yes"}},
+	})
+	// rules override headers set by the inner handler, whether they match the path or the type
+	tests := []struct {
+		path, csp, frame, video, cache string
+	}{
+		{"/index.html", "default-src 'self'", "DENY", "", "max-age=60"},
+		{"/videos/cats.html", "frame-src https://www.youtube.com", "DENY", "yes", "max-age=60"},
+		{"/embed.html", "default-src 'self'", "", "", "no-store"},
+		{"/logo.png", "", "DENY", "", "max-age=3600"},
+		{"/videos/cats.html?copy", "frame-src https://www.youtube.com", "DENY", "yes", "max-age=60"},
+		{"/logo.png?copy", "", "DENY", "", "max-age=3600"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if csp := w.Header().Get("Content-Security-Policy"); csp != test.csp {
+			t.Errorf("Expected Content-Security-Policy %q for %s but got %q", test.csp, test.path, csp)
+		}
+		if frame := w.Header().Get("X-Frame-Options"); frame != test.frame {
+			t.Errorf("Expected X-Frame-Options %q for %s but got %q", test.frame, test.path, frame)
+		}
+		if video := w.Header().Get("X-Video"); video != test.video {
+			t.Errorf("Expected X-Video %q for %s but got %q", test.video, test.path, video)
+		}
+		if cache := w.Header().Get("Cache-Control"); cache != test.cache {
+			t.Errorf("Expected Cache-Control %q for %s but got %q", test.cache, test.path, cache)
+		}
+	}
+}
+
+func TestAssetRedirectHandler(t *testing.T) {
+	fsys := fstest.MapFS{"static/site.0123456789abcdef.css": {Data: []byte("body{}")}}
+	h := AssetRedirectHandler(http.FileServer(http.FS(fsys)), fsys, func(p string) (string, error) {
+		if p == "/static/site.fedcba9876543210.css" {
+			return "/static/site.0123456789abcdef.css", nil
+		}
+		return "", fs.ErrNotExist
+	})
+	tests := map[string]int{
+		"/static/site.0123456789abcdef.css": http.StatusOK,
+		"/static/site.fedcba9876543210.css": http.StatusFound,
+		"/static/gone.fedcba9876543210.css": http.StatusNotFound,
+	}
+	for p, status := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, p, nil))
+		if w.Code != status {
+			t.Errorf("Expected %d for %s but got %d", status, p, w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/site.fedcba9876543210.css", nil))
+	if loc := w.Header().Get("Location"); loc != "/static/site.0123456789abcdef.css" {
+		t.Errorf("Unexpected location %q", loc)
+	}
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// HealthHandler reports that the server is alive, for use as a liveness probe.
+func HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte("ok\n"))
+	})
+}
+
+// ReadyHandler reports whether the server is ready for requests, for use as a
+// readiness probe. It responds with 503 Service Unavailable and the error when
+// ready returns one.
+func ReadyHandler(ready func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := ready(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(err.Error() + "\n"))
+			return
+		}
+		w.Write([]byte("ok\n"))
+	})
+}
+
+// Version holds build information reported by VersionHandler.
+type Version struct {
+	Path      string `json:"path"`               // Module path of the program
+	Version   string `json:"version"`            // Module version, or "(devel)" when built from source
+	GoVersion string `json:"goVersion"`          // Go version used to build
+	Revision  string `json:"revision,omitempty"` // Version control revision
+	Time      string `json:"time,omitempty"`     // Time of the revision
+	Modified  bool   `json:"modified,omitempty"` // Whether the source had uncommitted changes
+}
+
+// buildVersion returns the build information of the running program.
+func buildVersion() Version {
+	v := Version{GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	v.Path = info.Main.Path
+	v.Version = info.Main.Version
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			v.Revision = s.Value
+		case "vcs.time":
+			v.Time = s.Value
+		case "vcs.modified":
+			v.Modified = s.Value == "true"
+		}
+	}
+	return v
+}
+
+// VersionHandler responds with the build information of the program as JSON.
+func VersionHandler() http.Handler {
+	b, _ := json.Marshal(buildVersion())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	})
+}
@@ -0,0 +1,41 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	var readyErr error
+	h := EndpointHandler(http.NotFoundHandler(), map[string]http.Handler{
+		"/healthz": HealthHandler(),
+		"/readyz":  ReadyHandler(func() error { return readyErr }),
+		"/version": VersionHandler(),
+	})
+	get := func(p string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, p, nil))
+		return w
+	}
+	if w := get("/healthz"); w.Code != http.StatusOK {
+		t.Errorf("Expected healthz to be OK but got %d", w.Code)
+	}
+	if w := get("/readyz"); w.Code != http.StatusOK {
+		t.Errorf("Expected readyz to be OK but got %d", w.Code)
+	}
+	readyErr = errors.New("waiting for files")
+	if w := get("/readyz"); w.Code != http.StatusServiceUnavailable || w.Body.String() != "waiting for files\n" {
+		t.Errorf("Expected readyz to be unavailable but got %d %q", w.Code, w.Body.String())
+	}
+	var v Version
+	if err := json.Unmarshal(get("/version").Body.Bytes(), &v); err != nil || v.GoVersion != runtime.Version() {
+		t.Errorf("Unexpected version %+v: %v", v, err)
+	}
+	if w := get("/other"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected other paths to be passed on but got %d", w.Code)
+	}
+}
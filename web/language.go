@@ -0,0 +1,93 @@
+package web
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LanguageHandler serves pages in the visitor's preferred language based on the
+// Accept-Language header. languages lists the languages of the site, starting with
+// the default. Translated pages are found in a folder named for the language,
+// like "/es/about.html", and are served in place of the requested page when present
+// in fsys. Paths that already start with a language folder are not changed.
+func LanguageHandler(h http.Handler, fsys fs.FS, languages []string) http.Handler {
+	if len(languages) < 2 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Path
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) ||
+			!(strings.HasSuffix(p, "/") || strings.HasSuffix(p, ".html")) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		first, _, _ := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+		for _, lang := range languages {
+			if first == lang {
+				h.ServeHTTP(w, r)
+				return
+			}
+		}
+		w.Header().Add("Vary", "Accept-Language")
+		lang := preferredLanguage(r.Header.Get("Accept-Language"), languages)
+		if lang == "" || lang == languages[0] {
+			h.ServeHTTP(w, r)
+			return
+		}
+		name := path.Join(lang, p)
+		if strings.HasSuffix(p, "/") {
+			name = path.Join(name, "index.html")
+		}
+		if _, err := fs.Stat(fsys, name); err != nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = "/" + lang + p
+		r2.URL.RawPath = ""
+		w.Header().Set("Content-Language", lang)
+		h.ServeHTTP(w, r2)
+	})
+}
+
+// preferredLanguage returns the best of the given languages for the
+// Accept-Language header, or an empty string if none are acceptable.
+// A language like "es" matches requests for regional variants like "es-MX".
+func preferredLanguage(header string, languages []string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var accept []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			accept = append(accept, weighted{tag: strings.ToLower(tag), q: q})
+		}
+	}
+	sort.SliceStable(accept, func(i, j int) bool { return accept[i].q > accept[j].q })
+	for _, a := range accept {
+		for _, lang := range languages {
+			l := strings.ToLower(lang)
+			if a.tag == l || strings.HasPrefix(a.tag, l+"-") {
+				return lang
+			}
+		}
+	}
+	return ""
+}
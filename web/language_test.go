@@ -0,0 +1,57 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPreferredLanguage(t *testing.T) {
+	languages := []string{"en", "es"}
+	tests := map[string]string{
+		"":                        "",
+		"es":                      "es",
+		"es-MX,en;q=0.5":          "es",
+		"en-US,en;q=0.9,es;q=0.8": "en",
+		"fr,es;q=0.3":             "es",
+		"fr":                      "",
+		"en;q=0.2, ES;q=0.7":      "es",
+		"es;q=0, en":              "en",
+	}
+	for header, expect := range tests {
+		if lang := preferredLanguage(header, languages); lang != expect {
+			t.Errorf("%q: expected %q but got %q", header, expect, lang)
+		}
+	}
+}
+
+func TestLanguageHandler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"es/about.html": &fstest.MapFile{},
+		"es/index.html": &fstest.MapFile{},
+	}
+	var served string
+	h := LanguageHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = r.URL.Path
+	}), fsys, []string{"en", "es"})
+
+	tests := []struct {
+		path, accept, expect string
+	}{
+		{"/about.html", "es", "/es/about.html"},
+		{"/", "es-ES", "/es/"},
+		{"/how.html", "es", "/how.html"},
+		{"/about.html", "en", "/about.html"},
+		{"/es/about.html", "en", "/es/about.html"},
+		{"/static/dog.png", "es", "/static/dog.png"},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, test.path, nil)
+		r.Header.Set("Accept-Language", test.accept)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		if served != test.expect {
+			t.Errorf("%s (%s): expected %q but got %q", test.path, test.accept, test.expect, served)
+		}
+	}
+}
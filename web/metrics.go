@@ -0,0 +1,297 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/groupcache"
+)
+
+// durationBuckets are the upper bounds in seconds of the duration histograms.
+var durationBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogram counts durations in durationBuckets.
+type histogram struct {
+	buckets []int64
+	count   int64
+	sum     float64
+}
+
+// observe adds a duration to the histogram.
+func (h *histogram) observe(d time.Duration) {
+	if h.buckets == nil {
+		h.buckets = make([]int64, len(durationBuckets))
+	}
+	s := d.Seconds()
+	for i, le := range durationBuckets {
+		if s <= le {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += s
+}
+
+// requestKey identifies the requests counted together.
+type requestKey struct {
+	code        int
+	contentType string
+}
+
+// Metrics collects statistics about requests, rendering, template reloading, and
+// the cache, and serves them in the Prometheus text format. It implements the
+// virtual.Observer interface to receive rendering and template reloading events.
+type Metrics struct {
+	groupName string
+	requests  map[requestKey]*histogram
+	renders   map[string]*histogram
+	reloads   map[string]int64
+	mutex     sync.Mutex
+}
+
+// NewMetrics returns Metrics that include the statistics of the named groupcache group.
+func NewMetrics(groupName string) *Metrics {
+	return &Metrics{
+		groupName: groupName,
+		requests:  make(map[requestKey]*histogram),
+		renders:   make(map[string]*histogram),
+		reloads:   make(map[string]int64),
+	}
+}
+
+// MetricsHandler records the status code, content type, and duration of requests to h in m.
+func MetricsHandler(h http.Handler, m *Metrics) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		h.ServeHTTP(sw, r)
+		m.request(sw.Status(), w.Header().Get("Content-Type"), time.Since(start))
+	})
+}
+
+// request records a request.
+func (m *Metrics) request(code int, contentType string, d time.Duration) {
+	if ct, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = ct
+	}
+	k := requestKey{code: code, contentType: contentType}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	h, ok := m.requests[k]
+	if !ok {
+		h = &histogram{}
+		m.requests[k] = h
+	}
+	h.observe(d)
+}
+
+// Rendered records the time taken to render a page of the given kind.
+func (m *Metrics) Rendered(kind string, d time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	h, ok := m.renders[kind]
+	if !ok {
+		h = &histogram{}
+		m.renders[kind] = h
+	}
+	h.observe(d)
+}
+
+// TemplatesLoaded counts template reloads by whether they succeeded.
+func (m *Metrics) TemplatesLoaded(err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.reloads[result]++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	var b bytes.Buffer
+	m.write(&b)
+	w.Write(b.Bytes())
+}
+
+// write writes the metrics in the Prometheus text format.
+func (m *Metrics) write(w io.Writer) {
+	m.mutex.Lock()
+	requests := make(map[string]*histogram, len(m.requests))
+	for k, h := range m.requests {
+		requests[labels("code", strconv.Itoa(k.code), "content_type", k.contentType)] = h
+	}
+	writeHistograms(w, "whisper_http_request_duration_seconds", "Duration of HTTP requests by status code and content type.", requests)
+	renders := make(map[string]*histogram, len(m.renders))
+	for kind, h := range m.renders {
+		renders[labels("kind", kind)] = h
+	}
+	writeHistograms(w, "whisper_render_duration_seconds", "Duration of rendering pages by kind.", renders)
+	reloads := make(map[string]float64, len(m.reloads))
+	for result, n := range m.reloads {
+		reloads[labels("result", result)] = float64(n)
+	}
+	m.mutex.Unlock()
+	writeMetric(w, "whisper_template_reloads_total", "counter", "Template reloads by result.", reloads)
+
+	g := groupcache.GetGroup(m.groupName)
+	if g == nil {
+		return
+	}
+	stats := map[string]map[string]float64{
+		"items":     {},
+		"bytes":     {},
+		"gets":      {},
+		"hits":      {},
+		"evictions": {},
+	}
+	for _, c := range []struct {
+		name string
+		typ  groupcache.CacheType
+	}{{"main", groupcache.MainCache}, {"hot", groupcache.HotCache}} {
+		s := g.CacheStats(c.typ)
+		l := labels("cache", c.name)
+		stats["items"][l] = float64(s.Items)
+		stats["bytes"][l] = float64(s.Bytes)
+		stats["gets"][l] = float64(s.Gets)
+		stats["hits"][l] = float64(s.Hits)
+		stats["evictions"][l] = float64(s.Evictions)
+	}
+	writeMetric(w, "whisper_cache_items", "gauge", "Items in the cache.", stats["items"])
+	writeMetric(w, "whisper_cache_bytes", "gauge", "Bytes in the cache.", stats["bytes"])
+	writeMetric(w, "whisper_cache_gets_total", "counter", "Cache lookups.", stats["gets"])
+	writeMetric(w, "whisper_cache_hits_total", "counter", "Cache hits.", stats["hits"])
+	writeMetric(w, "whisper_cache_evictions_total", "counter", "Cache evictions.", stats["evictions"])
+	writeMetric(w, "whisper_cache_loads_total", "counter", "Files loaded into the cache after a miss.", map[string]float64{"": float64(g.Stats.LocalLoads.Get())})
+	writeMetric(w, "whisper_cache_load_errors_total", "counter", "Files that failed to load into the cache.", map[string]float64{"": float64(g.Stats.LocalLoadErrs.Get())})
+}
+
+// labels formats label pairs like {code="200",content_type="text/html"}.
+func labels(pairs ...string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// withLabel adds a label to a label string made by labels.
+func withLabel(l, name, value string) string {
+	extra := labels(name, value)
+	if l == "" || l == "{}" {
+		return extra
+	}
+	return strings.TrimSuffix(l, "}") + "," + extra[1:]
+}
+
+// sortedKeys returns the keys of a map in order, so that the output is stable.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeMetric writes a counter or gauge with a value for each label string.
+func writeMetric(w io.Writer, name, typ, help string, values map[string]float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	for _, l := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s%s %s\n", name, l, formatFloat(values[l]))
+	}
+}
+
+// writeHistograms writes a histogram for each label string.
+func writeHistograms(w io.Writer, name, help string, values map[string]*histogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for _, l := range sortedKeys(values) {
+		h := values[l]
+		for i, le := range durationBuckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(l, "le", formatFloat(le)), h.buckets[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(l, "le", "+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, l, formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, l, h.count)
+	}
+}
+
+// formatFloat formats a value for the Prometheus text format.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// statusWriter records the status code and size of a response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// WriteHeader records the status code.
+func (w *statusWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write records the number of bytes written.
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom records the number of bytes copied, and lets the underlying
+// ResponseWriter use sendfile.
+func (w *statusWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := io.Copy(w.ResponseWriter, r)
+	w.bytes += n
+	return n, err
+}
+
+// Flush flushes the underlying ResponseWriter.
+func (w *statusWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Status returns the status code of the response.
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Unwrap returns the underlying ResponseWriter for use by http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics("")
+	h := MetricsHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.html" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<h1>Hi</h1>"))
+	}), m)
+	for _, p := range []string{"/", "/index.html", "/missing.html"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, p, nil))
+	}
+	m.Rendered("markdown", 3*time.Millisecond)
+	m.TemplatesLoaded(nil)
+	m.TemplatesLoaded(errors.New("bad template"))
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	out := w.Body.String()
+	for _, expect := range []string{
+		"# TYPE whisper_http_request_duration_seconds histogram\n",
+		`whisper_http_request_duration_seconds_count{code="200",content_type="text/html"} 2` + "\n",
+		`whisper_http_request_duration_seconds_count{code="404",content_type="text/plain"} 1` + "\n",
+		`whisper_render_duration_seconds_bucket{kind="markdown",le="0.0025"} 0` + "\n",
+		`whisper_render_duration_seconds_bucket{kind="markdown",le="0.005"} 1` + "\n",
+		`whisper_render_duration_seconds_bucket{kind="markdown",le="+Inf"} 1` + "\n",
+		`whisper_render_duration_seconds_sum{kind="markdown"} 0.003` + "\n",
+		`whisper_template_reloads_total{result="failure"} 1` + "\n",
+		`whisper_template_reloads_total{result="success"} 1` + "\n",
+	} {
+		if !strings.Contains(out, expect) {
+			t.Errorf("Expected %q in metrics:\n%s", expect, out)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	if l := labels("a", "x", "b", `say "hi"\n`); l != `{a="x",b="say \"hi\"\\n"}` {
+		t.Errorf("Unexpected labels %s", l)
+	}
+	if l := withLabel(labels("a", "x"), "le", "1"); l != `{a="x",le="1"}` {
+		t.Errorf("Unexpected labels %s", l)
+	}
+	if l := withLabel("", "le", "1"); l != `{le="1"}` {
+		t.Errorf("Unexpected labels %s", l)
+	}
+}
+
+func TestStatusWriter(t *testing.T) {
+	w := httptest.NewRecorder()
+	sw := &statusWriter{ResponseWriter: w}
+	n, err := sw.ReadFrom(strings.NewReader("hello"))
+	if err != nil || n != 5 || sw.bytes != 5 || sw.Status() != http.StatusOK {
+		t.Errorf("Expected 5 bytes copied with status 200 but got %d, %d, %d, %v", n, sw.bytes, sw.Status(), err)
+	}
+	if err = http.NewResponseController(sw).Flush(); err != nil || !w.Flushed {
+		t.Errorf("Expected the response to be flushed: %v", err)
+	}
+}
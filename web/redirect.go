@@ -0,0 +1,111 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redirect is a rule for RedirectHandler. From is a path to match, where a segment
+// like ":year" matches any one segment and a trailing "*" matches the rest of the
+// path. To is the path or URL to send to, and may use the captured segments by
+// name, with ":splat" for the rest of the path.
+type Redirect struct {
+	From   string // Path to match, like "/blog/:year/*"
+	To     string // Path or URL to send to, like "/posts/:year/:splat"
+	Status int    // Redirect status code, 301 if zero, or 200 to rewrite the path internally
+}
+
+// placeholder matches the names of captured segments in a target, like ":year".
+var placeholder = regexp.MustCompile(`:[A-Za-z_][A-Za-z0-9_]*`)
+
+// RedirectHandler checks the rules in order, redirecting requests that match
+// or, for a status of 200, serving the target path in place of the request.
+// Other requests are passed to h. The query string is kept unless the target
+// has one. Captured segments are escaped, and leading slashes of a target path
+// are collapsed so that it cannot redirect to another host.
+func RedirectHandler(h http.Handler, rules []Redirect) http.Handler {
+	if len(rules) == 0 {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, rule := range rules {
+			params, ok := matchPath(rule.From, r.URL.Path)
+			if !ok {
+				continue
+			}
+			target := placeholder.ReplaceAllStringFunc(rule.To, func(s string) string {
+				if v, ok := params[s[1:]]; ok {
+					return escapeSegments(v)
+				}
+				return s
+			})
+			// a target like "//example.com" would leave the site
+			if strings.HasPrefix(target, "/") {
+				target = "/" + strings.TrimLeft(target, "/")
+			}
+			if rule.Status == http.StatusOK {
+				u, err := url.Parse(target)
+				if err != nil {
+					http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+					return
+				}
+				r2 := r.Clone(r.Context())
+				r2.URL.Path, r2.URL.RawPath = u.Path, u.RawPath
+				if u.RawQuery != "" {
+					r2.URL.RawQuery = u.RawQuery
+				}
+				h.ServeHTTP(w, r2)
+				return
+			}
+			if !strings.Contains(target, "?") && r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			status := rule.Status
+			if status == 0 {
+				status = http.StatusMovedPermanently
+			}
+			http.Redirect(w, r, target, status)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// escapeSegments escapes each segment of a captured path, so that it cannot
+// add a query string or a fragment to the target.
+func escapeSegments(p string) string {
+	segs := strings.Split(p, "/")
+	for i, seg := range segs {
+		segs[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segs, "/")
+}
+
+// matchPath matches the path against the pattern, returning the captured segments.
+func matchPath(pattern, p string) (map[string]string, bool) {
+	params := make(map[string]string)
+	pp := strings.Split(pattern, "/")
+	ps := strings.Split(p, "/")
+	for i, seg := range pp {
+		if seg == "*" && i == len(pp)-1 {
+			// earlier segments matched, so i is at most len(ps)
+			params["splat"] = strings.Join(ps[i:], "/")
+			return params, true
+		}
+		if i >= len(ps) {
+			return nil, false
+		}
+		switch {
+		case strings.HasPrefix(seg, ":") && len(seg) > 1:
+			if ps[i] == "" {
+				return nil, false
+			}
+			params[seg[1:]] = ps[i]
+		case seg != ps[i]:
+			return nil, false
+		}
+	}
+	return params, len(pp) == len(ps)
+}
@@ -0,0 +1,49 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	h := RedirectHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + "?" + r.URL.RawQuery))
+	}), []Redirect{
+		{From: "/old.html", To: "/new.html"},
+		{From: "/news/*", To: "/blog/:splat", Status: http.StatusFound},
+		{From: "/posts/:year/:slug", To: "https://example.com/:year/:slug", Status: http.StatusPermanentRedirect},
+		{From: "/app/*", To: "/app/index.html", Status: http.StatusOK},
+		{From: "/old/*", To: "/:splat"},
+		{From: "/docs/:page", To: "/manual/:page", Status: http.StatusOK},
+	})
+	tests := []struct {
+		path, location, body string
+		status               int
+	}{
+		{"/old.html", "/new.html", "", http.StatusMovedPermanently},
+		{"/news/2024/a.html?x=1", "/blog/2024/a.html?x=1", "", http.StatusFound},
+		{"/news", "/blog/", "", http.StatusFound},
+		{"/posts/2024/hello.html", "https://example.com/2024/hello.html", "", http.StatusPermanentRedirect},
+		{"/posts/2024", "", "/posts/2024?", http.StatusOK},
+		{"/app/settings/profile?tab=1", "", "/app/index.html?tab=1", http.StatusOK},
+		{"/other.html", "", "/other.html?", http.StatusOK},
+		{"/old//evil.com", "/evil.com", "", http.StatusMovedPermanently},
+		{"/old/%2Fevil.com", "/evil.com", "", http.StatusMovedPermanently},
+		{"/old/a%3Fb=c", "/a%3Fb=c", "", http.StatusMovedPermanently},
+		{"/docs/a%3Fb=c", "", "/manual/a?b=c?", http.StatusOK},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != test.status {
+			t.Errorf("Expected status %d for %s but got %d", test.status, test.path, w.Code)
+		}
+		if loc := w.Header().Get("Location"); loc != test.location {
+			t.Errorf("Expected location %q for %s but got %q", test.location, test.path, loc)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("Expected body %q for %s but got %q", test.body, test.path, w.Body.String())
+		}
+	}
+}
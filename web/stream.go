@@ -0,0 +1,41 @@
+package web
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// StreamHandler serves regular files of at least minSize bytes directly from fsys,
+// bypassing h. This keeps large media like video out of the cache, while
+// http.ServeContent still handles Range and If-Range requests. Pages and
+// folders are always passed to h.
+func StreamHandler(h http.Handler, fsys fs.FS, minSize int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if minSize <= 0 || (r.Method != http.MethodGet && r.Method != http.MethodHead) ||
+			strings.HasSuffix(r.URL.Path, "/") || strings.HasSuffix(r.URL.Path, ".html") {
+			h.ServeHTTP(w, r)
+			return
+		}
+		name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+		fi, err := fs.Stat(fsys, name)
+		if err != nil || !fi.Mode().IsRegular() || fi.Size() < minSize {
+			h.ServeHTTP(w, r)
+			return
+		}
+		f, err := fsys.Open(name)
+		if err != nil {
+			h.ServeHTTP(w, r)
+			return
+		}
+		defer f.Close()
+		rs, ok := f.(io.ReadSeeker)
+		if !ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		http.ServeContent(w, r, fi.Name(), fi.ModTime(), rs)
+	})
+}
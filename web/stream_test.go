@@ -0,0 +1,52 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStreamHandler(t *testing.T) {
+	fsys := fstest.MapFS{
+		"videos/big.mp4":   &fstest.MapFile{Data: []byte(strings.Repeat("0123456789", 100))},
+		"videos/small.mp4": &fstest.MapFile{Data: []byte("0123456789")},
+	}
+	var inner bool
+	h := StreamHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner = true
+	}), fsys, 100)
+
+	tests := []struct {
+		path   string
+		rng    string
+		inner  bool
+		status int
+		body   string
+	}{
+		{"/videos/big.mp4", "", false, http.StatusOK, strings.Repeat("0123456789", 100)},
+		{"/videos/big.mp4", "bytes=10-14", false, http.StatusPartialContent, "01234"},
+		{"/videos/small.mp4", "", true, http.StatusOK, ""},
+		{"/videos/missing.mp4", "", true, http.StatusOK, ""},
+		{"/videos/", "", true, http.StatusOK, ""},
+	}
+	for _, test := range tests {
+		inner = false
+		r := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.rng != "" {
+			r.Header.Set("Range", test.rng)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if inner != test.inner {
+			t.Errorf("%s: expected inner handler called to be %v", test.path, test.inner)
+		}
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d but got %d", test.path, test.status, w.Code)
+		}
+		if w.Body.String() != test.body {
+			t.Errorf("%s: unexpected body %q", test.path, w.Body.String())
+		}
+	}
+}
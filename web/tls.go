@@ -0,0 +1,140 @@
+package web
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Certificate is a TLS certificate loaded from PEM files, which can be reloaded
+// when the files change so that renewed certificates are used without a restart.
+type Certificate struct {
+	certFile string
+	keyFile  string
+	cert     *tls.Certificate
+	modTime  time.Time // latest modification time of the files when they were loaded
+	mutex    sync.RWMutex
+	done     chan bool // used to stop the reloader
+}
+
+// LoadCertificate loads a certificate and its private key from PEM files.
+func LoadCertificate(certFile, keyFile string) (*Certificate, error) {
+	c := &Certificate{certFile: certFile, keyFile: keyFile}
+	_, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// GetCertificate returns the current certificate. It is meant to be used as
+// the GetCertificate function of a tls.Config.
+func (c *Certificate) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.cert, nil
+}
+
+// Reload checks the files every interval and loads them again when they change.
+func (c *Certificate) Reload(interval time.Duration) {
+	if interval > 0 {
+		c.done = make(chan bool)
+		go c.reload(interval)
+	}
+}
+
+// Close stops reloading the certificate.
+func (c *Certificate) Close() error {
+	if c.done != nil {
+		c.done <- true
+	}
+	return nil
+}
+
+// reload is started as a goroutine to periodically reload the certificate
+// in case it was renewed. If loading fails, the previous certificate is kept.
+func (c *Certificate) reload(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-t.C:
+			loaded, err := c.load()
+			if err != nil {
+				slog.Error("Failed to load certificate", "error", err)
+			} else if loaded {
+				slog.Info("Loaded certificate", "file", c.certFile)
+			}
+		}
+	}
+}
+
+// load loads the certificate if the files changed since they were last loaded,
+// returning true if they were loaded.
+func (c *Certificate) load() (bool, error) {
+	var modTime time.Time
+	for _, name := range []string{c.certFile, c.keyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return false, fmt.Errorf("load certificate: %w", err)
+		}
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	c.mutex.RLock()
+	unchanged := c.cert != nil && modTime.Equal(c.modTime)
+	c.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("load certificate: %w", err)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.cert = &cert
+	c.modTime = modTime
+	return true, nil
+}
+
+// HTTPSRedirectHandler redirects requests to the same URL using HTTPS on the given
+// public port, which is left out of the URL if it is 443 or 0.
+func HTTPSRedirectHandler(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		if port != 443 && port != 0 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		code := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+	})
+}
+
+// TLSConfig returns a TLS configuration using the given certificate, which
+// requires TLS 1.2 or later.
+func TLSConfig(c *Certificate) *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.GetCertificate,
+	}
+}
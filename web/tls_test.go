@@ -0,0 +1,173 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate for localhost and its key to the given files.
+func writeCertificate(t *testing.T, certFile, keyFile string, serial int64, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: der},
+		keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	}
+	for name, block := range files {
+		if err = os.WriteFile(name, pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.Chtimes(name, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if _, err := LoadCertificate(certFile, keyFile); err == nil {
+		t.Errorf("Expected error for missing files")
+	}
+	writeCertificate(t, certFile, keyFile, 1, time.Now().Add(-time.Minute))
+	c, err := LoadCertificate(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial := func() int64 {
+		cert, err := c.GetCertificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return x.SerialNumber.Int64()
+	}
+	if s := serial(); s != 1 {
+		t.Errorf("Expected serial 1 but got %d", s)
+	}
+	if loaded, err := c.load(); loaded || err != nil {
+		t.Errorf("Expected no reload of unchanged files: %v", err)
+	}
+
+	writeCertificate(t, certFile, keyFile, 2, time.Now())
+	if loaded, err := c.load(); !loaded || err != nil {
+		t.Errorf("Expected reload of changed files: %v", err)
+	}
+	if s := serial(); s != 2 {
+		t.Errorf("Expected serial 2 but got %d", s)
+	}
+
+	// a broken key keeps the previous certificate
+	if err = os.WriteFile(keyFile, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(keyFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.load(); err == nil {
+		t.Errorf("Expected error for broken key")
+	}
+	if s := serial(); s != 2 {
+		t.Errorf("Expected serial 2 but got %d", s)
+	}
+}
+
+func TestCertificateServer(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCertificate(t, certFile, keyFile, 1, time.Now())
+	c, err := LoadCertificate(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		}),
+		TLSConfig: TLSConfig(c),
+	}
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(certPEM)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool, ServerName: "localhost"},
+		ForceAttemptHTTP2: true,
+	}}
+	resp, err := client.Get("https://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.TLS.PeerCertificates[0].SerialNumber.Int64() != 1 {
+		t.Errorf("Expected the loaded certificate")
+	}
+	if resp.ProtoMajor != 2 {
+		t.Errorf("Expected HTTP/2 but got %s", resp.Proto)
+	}
+}
+
+func TestHTTPSRedirectHandler(t *testing.T) {
+	tests := []struct {
+		port      int
+		method    string
+		url       string
+		code      int
+		expectURL string
+	}{
+		{443, http.MethodGet, "http://example.com/a/b.html?x=1", http.StatusMovedPermanently, "https://example.com/a/b.html?x=1"},
+		{8443, http.MethodGet, "http://example.com:8080/", http.StatusMovedPermanently, "https://example.com:8443/"},
+		{443, http.MethodPost, "http://[::1]:8080/form", http.StatusPermanentRedirect, "https://[::1]/form"},
+		{0, http.MethodGet, "http://example.com:8080/", http.StatusMovedPermanently, "https://example.com/"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		HTTPSRedirectHandler(test.port).ServeHTTP(w, httptest.NewRequest(test.method, test.url, nil))
+		if w.Code != test.code || w.Header().Get("Location") != test.expectURL {
+			t.Errorf("Expected %d to %s but got %d to %s", test.code, test.expectURL, w.Code, w.Header().Get("Location"))
+		}
+	}
+}